@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+// export prints the configuration of a datastore, of a module or of the
+// nodes selected by an xpath.
+func export(args []string) error {
+	flags, opts := newFlagSet("export", "running")
+	flags.StringVar(&opts.module, "m", "", "export only the data of `module`")
+	xpath := flags.String("x", "", "export only the data selected by `xpath`")
+	output := flags.String("o", "", "output `file`, standard output if empty")
+	flags.Parse(args)
+
+	selection := "/*"
+	switch {
+	case *xpath != "" && opts.module != "":
+		return errors.New("-m and -x are mutually exclusive")
+	case *xpath != "":
+		selection = *xpath
+	case opts.module != "":
+		selection = "/" + opts.module + ":*"
+	}
+
+	format, err := opts.dataFormat(*output)
+	if err != nil {
+		return err
+	}
+
+	return withSession(opts, func(session *sysrepo.Session) error {
+		data, err := session.ExportData(selection, format, sysrepo.GetDefault, opts.timeout)
+		if err != nil {
+			return err
+		}
+		return writeOutput(*output, data)
+	})
+}
+
+// importConfig replaces the configuration of a datastore or a module, or
+// applies it with another default operation.
+func importConfig(args []string) error {
+	flags, opts := newFlagSet("import", "running")
+	flags.StringVar(&opts.module, "m", "", "import only the data of `module`")
+	operation := flags.String("op", string(sysrepo.OpReplace), "default `operation`, replace, merge or none")
+	flags.Parse(args)
+
+	defaultOperation, err := parseDefaultOperation(*operation)
+	if err != nil {
+		return err
+	}
+
+	file, data, err := readInput(flags)
+	if err != nil {
+		return err
+	}
+
+	format, err := opts.dataFormat(file)
+	if err != nil {
+		return err
+	}
+
+	return withSession(opts, func(session *sysrepo.Session) error {
+		if defaultOperation == sysrepo.OpReplace {
+			return session.ReplaceConfig(opts.moduleName(), data, format, opts.timeout)
+		}
+		if opts.module != "" {
+			return errors.New("-m can only be used with the replace operation")
+		}
+		return applyEdit(session, data, format, defaultOperation, opts)
+	})
+}
+
+// edit applies a NETCONF edit-config content to a datastore.
+func edit(args []string) error {
+	flags, opts := newFlagSet("edit", "running")
+	operation := flags.String("op", string(sysrepo.OpMerge), "default `operation`, merge, replace or none")
+	flags.Parse(args)
+
+	defaultOperation, err := parseDefaultOperation(*operation)
+	if err != nil {
+		return err
+	}
+
+	file, data, err := readInput(flags)
+	if err != nil {
+		return err
+	}
+
+	format, err := opts.dataFormat(file)
+	if err != nil {
+		return err
+	}
+
+	return withSession(opts, func(session *sysrepo.Session) error {
+		return applyEdit(session, data, format, defaultOperation, opts)
+	})
+}
+
+func applyEdit(session *sysrepo.Session, data string, format sysrepo.DataFormat, defaultOperation sysrepo.DefaultOperation, opts *options) error {
+	err := session.EditBatch(data, format, defaultOperation)
+	if err == nil {
+		err = session.ApplyChanges(opts.timeout)
+	}
+	if err != nil {
+		return errors.Join(err, session.DiscardChanges(nil))
+	}
+	return nil
+}
+
+// copyConfig replaces the configuration of a datastore with the one of
+// another.
+func copyConfig(args []string) error {
+	flags, opts := newFlagSet("copy-config", "running")
+	flags.StringVar(&opts.module, "m", "", "copy only the data of `module`")
+	source := flags.String("s", "", "source `datastore`")
+	flags.Parse(args)
+
+	if *source == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	sourceDs, err := parseDatastore(*source)
+	if err != nil {
+		return err
+	}
+
+	return withSession(opts, func(session *sysrepo.Session) error {
+		return session.CopyConfig(sourceDs, opts.moduleName(), opts.timeout)
+	})
+}
+
+// get prints the configuration and state data selected by an xpath.
+func get(args []string) error {
+	flags, opts := newFlagSet("get", "operational")
+	xpath := flags.String("x", "", "`xpath` selecting the data")
+	noState := flags.Bool("no-state", false, "leave out state data")
+	flags.Parse(args)
+
+	if *xpath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	format, err := opts.dataFormat("")
+	if err != nil {
+		return err
+	}
+
+	getOpts := sysrepo.GetDefault
+	if *noState {
+		getOpts = sysrepo.GetOperNoState
+	}
+
+	return withSession(opts, func(session *sysrepo.Session) error {
+		data, err := session.ExportData(*xpath, format, getOpts, opts.timeout)
+		if err != nil {
+			return err
+		}
+		return writeOutput("", data)
+	})
+}
+
+func writeOutput(file string, data string) error {
+	if file == "" {
+		_, err := fmt.Fprint(os.Stdout, data)
+		return err
+	}
+	return os.WriteFile(file, []byte(data), 0644)
+}
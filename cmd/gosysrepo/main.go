@@ -0,0 +1,171 @@
+// Command gosysrepo reads and modifies sysrepo datastores, in the manner of
+// sysrepocfg.
+//
+// Usage:
+//
+//	gosysrepo export [-d datastore] [-f format] [-m module] [-x xpath] [-o file]
+//	gosysrepo import [-d datastore] [-f format] [-m module] [-op operation] [file]
+//	gosysrepo edit [-d datastore] [-f format] [-op operation] [file]
+//	gosysrepo copy-config -s datastore [-d datastore] [-m module]
+//	gosysrepo get [-d datastore] [-f format] -x xpath
+//
+// The datastore is running, startup, candidate, operational or
+// factory-default. The format is xml or json, guessed from the file name if
+// not given. Import replaces the configuration unless another default
+// operation is given, while edit merges it. Data is read from standard input
+// if no file is given. All commands accept -t to set the timeout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+var commands = map[string]func(args []string) error{
+	"export":      export,
+	"import":      importConfig,
+	"edit":        edit,
+	"copy-config": copyConfig,
+	"get":         get,
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gosysrepo: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	err := command(flag.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gosysrepo:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gosysrepo export|import|edit|copy-config|get [flags]")
+	fmt.Fprintln(os.Stderr, "Run 'gosysrepo <command> -h' for the flags of a command.")
+}
+
+// options are the flags shared by the commands.
+type options struct {
+	datastore string
+	format    string
+	module    string
+	timeout   time.Duration
+}
+
+func newFlagSet(name string, defaultDatastore string) (*flag.FlagSet, *options) {
+	opts := &options{}
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.StringVar(&opts.datastore, "d", defaultDatastore, "`datastore` to use")
+	flags.StringVar(&opts.format, "f", "", "data `format`, xml or json")
+	flags.DurationVar(&opts.timeout, "t", 0, "`timeout` of the operation, the sysrepo default if 0")
+	return flags, opts
+}
+
+func (o *options) moduleName() *string {
+	if o.module == "" {
+		return nil
+	}
+	return &o.module
+}
+
+// dataFormat returns the format of the data in file, XML unless the format
+// flag or the file extension say otherwise.
+func (o *options) dataFormat(file string) (sysrepo.DataFormat, error) {
+	format := o.format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(file), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case "", "xml":
+		return sysrepo.FormatXML, nil
+	case "json":
+		return sysrepo.FormatJSON, nil
+	}
+	if o.format == "" {
+		return sysrepo.FormatXML, nil
+	}
+	return 0, fmt.Errorf("unknown format %q", o.format)
+}
+
+func parseDatastore(name string) (sysrepo.Datastore, error) {
+	switch name {
+	case "running":
+		return sysrepo.DSRunning, nil
+	case "startup":
+		return sysrepo.DSStartup, nil
+	case "candidate":
+		return sysrepo.DSCandidate, nil
+	case "operational":
+		return sysrepo.DSOperational, nil
+	case "factory-default":
+		return sysrepo.DSFactoryDefault, nil
+	}
+	return 0, fmt.Errorf("unknown datastore %q", name)
+}
+
+func parseDefaultOperation(name string) (sysrepo.DefaultOperation, error) {
+	switch op := sysrepo.DefaultOperation(name); op {
+	case sysrepo.OpMerge, sysrepo.OpReplace, sysrepo.OpNone:
+		return op, nil
+	}
+	return "", fmt.Errorf("unknown default operation %q", name)
+}
+
+// withSession connects to sysrepo and runs fn in a session on the datastore
+// of opts.
+func withSession(opts *options, fn func(session *sysrepo.Session) error) error {
+	ds, err := parseDatastore(opts.datastore)
+	if err != nil {
+		return err
+	}
+
+	conn, err := sysrepo.Connect(sysrepo.ConnDefault)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	session, err := conn.SessionStart(ds)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return fn(session)
+}
+
+// readInput returns the content of the only argument, or of standard input if
+// there is none.
+func readInput(flags *flag.FlagSet) (string, string, error) {
+	switch flags.NArg() {
+	case 0:
+		data, err := io.ReadAll(os.Stdin)
+		return "", string(data), err
+	case 1:
+		data, err := os.ReadFile(flags.Arg(0))
+		return flags.Arg(0), string(data), err
+	}
+	flags.Usage()
+	os.Exit(2)
+	return "", "", nil
+}
@@ -0,0 +1,356 @@
+// Package gen generates Go source from the YANG schema model loaded by
+// sysrepo-gen. It doesn't depend on libyang, so it can be tested on its own.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// generator emits Go source for a set of modules: identity constants, enum
+// types, structs usable with sysrepo.Unmarshal and sysrepo.Marshal, and path
+// builders for the data nodes.
+//
+// All Go names are assigned before emitting anything. Names which would clash
+// get a numeric suffix and a warning, so the output always compiles.
+type generator struct {
+	pkg      string
+	body     bytes.Buffer
+	usesKeys bool
+	warnings []string
+
+	// declared maps the package-level identifiers to the YANG node or
+	// identity which claimed them.
+	declared map[string]string
+	// types are the struct or enum types of the nodes, paths the types of
+	// their path builders, roots the constructors of the top-level paths, and
+	// members the field and method names.
+	types   map[*Node]string
+	paths   map[*Node]string
+	roots   map[*Node]string
+	members map[*Node]string
+	// identities are the constants of the identities by qualified name, and
+	// enums the constants of the values of enumeration leaves.
+	identities map[string]string
+	enums      map[*Node][]string
+}
+
+// Generate returns the source of package pkg for modules, and warnings about
+// renamed or skipped nodes.
+func Generate(pkg string, modules []*Module) ([]byte, []string, error) {
+	g := &generator{
+		pkg:        pkg,
+		declared:   map[string]string{},
+		types:      map[*Node]string{},
+		paths:      map[*Node]string{},
+		roots:      map[*Node]string{},
+		members:    map[*Node]string{},
+		identities: map[string]string{},
+		enums:      map[*Node][]string{},
+	}
+	for _, mod := range modules {
+		g.nameModule(mod)
+	}
+	for _, mod := range modules {
+		g.module(mod)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by sysrepo-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if g.usesKeys {
+		out.WriteString("import (\n\"fmt\"\n\n\"github.com/mattiaswal/go-sysrepo/sysrepo/xpath\"\n)\n\n")
+	}
+	out.Write(g.body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	return src, g.warnings, err
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+func (g *generator) warnf(format string, args ...any) {
+	g.warnings = append(g.warnings, fmt.Sprintf(format, args...))
+}
+
+// declare reserves a package-level identifier for owner, adding a numeric
+// suffix if name is already taken.
+func (g *generator) declare(name string, owner string) string {
+	unique := uniqueName(name, func(candidate string) bool {
+		_, taken := g.declared[candidate]
+		return taken
+	})
+	if unique != name {
+		g.warnf("%s is named %s, as %s is used by %s", owner, unique, name, g.declared[name])
+	}
+	g.declared[unique] = owner
+	return unique
+}
+
+func uniqueName(name string, taken func(string) bool) string {
+	unique := name
+	for i := 2; taken(unique); i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	return unique
+}
+
+// nameModule assigns the Go names of the identities and data nodes of mod.
+func (g *generator) nameModule(mod *Module) {
+	for _, ident := range mod.Identities {
+		qualified := ident.Module + ":" + ident.Name
+		g.identities[qualified] = g.declare(goName(ident.Module)+goName(ident.Name), "identity "+qualified)
+	}
+
+	for _, node := range mod.Nodes {
+		if node.Kind != KindContainer && node.Kind != KindList {
+			g.warnf("top-level %s is skipped, only containers and lists are generated", schemaPath(node))
+			continue
+		}
+
+		g.nameNode(goName(node.Name), node)
+		g.roots[node] = g.declare("New"+g.paths[node], schemaPath(node)+" path constructor")
+	}
+}
+
+// nameNode assigns the names of a container or list entry and, recursively,
+// of its children.
+func (g *generator) nameNode(typeName string, node *Node) {
+	g.types[node] = g.declare(typeName, schemaPath(node))
+	g.paths[node] = g.declare(g.types[node]+"Path", schemaPath(node)+" path")
+	if node.Kind == KindList && !hasKeys(node) {
+		g.warnf("list %s has no keys, so Marshal can't address its entries and its path selects all of them", schemaPath(node))
+	}
+
+	fields := map[string]bool{}
+	for _, child := range node.Children {
+		name := goName(child.Name)
+		member := uniqueName(name, func(candidate string) bool { return fields[candidate] })
+		if member != name {
+			g.warnf("%s is named %s in %s, as %s is used by another child", schemaPath(child), member, g.types[node], name)
+		}
+		fields[member] = true
+		g.members[child] = member
+	}
+
+	for _, child := range node.Children {
+		childType := g.types[node] + g.members[child]
+		switch {
+		case child.Kind == KindContainer || child.Kind == KindList:
+			g.nameNode(childType, child)
+		case len(child.Type.Enums) > 0:
+			g.types[child] = g.declare(childType, schemaPath(child))
+			for _, enum := range child.Type.Enums {
+				g.enums[child] = append(g.enums[child], g.declare(g.types[child]+goName(enum), "enum "+enum+" of "+schemaPath(child)))
+			}
+		}
+	}
+}
+
+func (g *generator) module(mod *Module) {
+	if len(mod.Identities) > 0 {
+		g.printf("// Identities of %s.\nconst (\n", mod.Name)
+		for _, ident := range mod.Identities {
+			qualified := ident.Module + ":" + ident.Name
+			g.printf("%s = %q\n", g.identities[qualified], qualified)
+		}
+		g.printf(")\n\n")
+	}
+
+	for _, node := range mod.Nodes {
+		if node.Kind != KindContainer && node.Kind != KindList {
+			continue
+		}
+
+		g.structType(node)
+		g.rootPath(node)
+		g.pathType(node)
+	}
+}
+
+// structType emits the struct of a container or list entry and, recursively,
+// the types of its children.
+func (g *generator) structType(node *Node) {
+	typeName := g.types[node]
+	g.printf("// %s holds the data of %s.\ntype %s struct {\n", typeName, schemaPath(node), typeName)
+	for _, child := range node.Children {
+		g.printf("%s %s `yang:\"%s\"`\n", g.members[child], g.fieldType(child), fieldTag(node, child))
+	}
+	g.printf("}\n\n")
+
+	for _, child := range node.Children {
+		switch {
+		case child.Kind == KindContainer || child.Kind == KindList:
+			g.structType(child)
+		case len(child.Type.Enums) > 0:
+			g.enumType(child)
+		}
+	}
+}
+
+func (g *generator) fieldType(child *Node) string {
+	switch child.Kind {
+	case KindContainer:
+		if child.Presence {
+			return "*" + g.types[child]
+		}
+		return g.types[child]
+	case KindList:
+		return "[]" + g.types[child]
+	}
+
+	valueType := child.Type.Go
+	if len(child.Type.Enums) > 0 {
+		valueType = g.types[child]
+	}
+
+	switch {
+	case child.Kind == KindLeafList:
+		return "[]" + valueType
+	case child.Key || child.Type.Empty:
+		return valueType
+	default:
+		return "*" + valueType
+	}
+}
+
+func fieldTag(parent *Node, child *Node) string {
+	tag := child.Name
+	if child.Module != parent.Module {
+		tag = child.Module + ":" + child.Name
+	}
+
+	switch {
+	case child.Key:
+		tag += ",key"
+	case child.Type.Empty:
+		tag += ",empty"
+	}
+	return tag
+}
+
+func (g *generator) enumType(node *Node) {
+	typeName := g.types[node]
+	g.printf("// %s is a value of the enumeration %s.\ntype %s string\n\nconst (\n", typeName, schemaPath(node), typeName)
+	for i, enum := range node.Type.Enums {
+		g.printf("%s %s = %q\n", g.enums[node][i], typeName, enum)
+	}
+	g.printf(")\n\n")
+}
+
+// rootPath emits the constructor of the path of a top-level node.
+func (g *generator) rootPath(node *Node) {
+	pathName := g.paths[node]
+	params, predicates := g.keyParams(node)
+	g.printf("// %s returns the path of %s.\n", g.roots[node], schemaPath(node))
+	g.printf("func %s(%s) %s {\n", g.roots[node], params, pathName)
+	g.printf("return %s(%q%s)\n}\n\n", pathName, "/"+node.Module+":"+node.Name, predicates)
+}
+
+// pathType emits the path builder of a container or list entry and,
+// recursively, of its children.
+func (g *generator) pathType(node *Node) {
+	pathName := g.paths[node]
+	g.printf("// %s is the path of %s.\ntype %s string\n\n", pathName, schemaPath(node), pathName)
+
+	for _, child := range node.Children {
+		name := g.members[child]
+		step := "/" + child.Name
+		if child.Module != node.Module {
+			step = "/" + child.Module + ":" + child.Name
+		}
+
+		switch child.Kind {
+		case KindContainer, KindList:
+			params, predicates := g.keyParams(child)
+			g.printf("func (p %s) %s(%s) %s {\n", pathName, name, params, g.paths[child])
+			g.printf("return %s(string(p) + %q%s)\n}\n\n", g.paths[child], step, predicates)
+		default:
+			g.printf("func (p %s) %s() string {\nreturn string(p) + %q\n}\n\n", pathName, name, step)
+		}
+	}
+
+	for _, child := range node.Children {
+		if child.Kind == KindContainer || child.Kind == KindList {
+			g.pathType(child)
+		}
+	}
+}
+
+// keyParams returns the parameter list and the predicate expression selecting
+// a list entry by its keys. Both are empty for containers.
+func (g *generator) keyParams(node *Node) (string, string) {
+	if node.Kind != KindList {
+		return "", ""
+	}
+
+	var params []string
+	var predicates strings.Builder
+	used := map[string]bool{}
+	for _, child := range node.Children {
+		if !child.Key {
+			continue
+		}
+
+		param := uniqueName(paramName(g.members[child]), func(candidate string) bool { return used[candidate] })
+		used[param] = true
+		params = append(params, param+" "+g.fieldType(child))
+		fmt.Fprintf(&predicates, " + \"[%s=\" + xpath.Quote(fmt.Sprint(%s)) + \"]\"", child.Name, param)
+		g.usesKeys = true
+	}
+	return strings.Join(params, ", "), predicates.String()
+}
+
+func hasKeys(node *Node) bool {
+	for _, child := range node.Children {
+		if child.Key {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaPath(node *Node) string {
+	return node.Module + ":" + node.Name
+}
+
+// goName converts a YANG identifier into an exported Go identifier, e.g.
+// "oper-status" into "OperStatus".
+func goName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+
+	result := b.String()
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "N" + result
+	}
+	return result
+}
+
+// paramName returns the parameter name for a key, avoiding Go keywords, the
+// receiver p and the packages used by the path builders.
+func paramName(name string) string {
+	result := strings.ToLower(name[:1]) + name[1:]
+	switch result {
+	case "type", "interface", "func", "map", "range", "select", "default", "case", "chan", "go", "var", "const", "import", "package", "return", "struct", "switch", "for", "if", "else", "break", "continue", "defer", "fallthrough", "goto",
+		"p", "fmt", "xpath":
+		result += "Key"
+	}
+	return result
+}
@@ -0,0 +1,172 @@
+package gen
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func leaf(name string, goType string) *Node {
+	return &Node{Name: name, Module: "m", Kind: KindLeaf, Type: LeafType{Go: goType}}
+}
+
+func key(name string, goType string) *Node {
+	node := leaf(name, goType)
+	node.Key = true
+	return node
+}
+
+func enum(name string, values ...string) *Node {
+	return &Node{Name: name, Module: "m", Kind: KindLeaf, Type: LeafType{Go: "string", Enums: values}}
+}
+
+func container(name string, children ...*Node) *Node {
+	return &Node{Name: name, Module: "m", Kind: KindContainer, Children: children}
+}
+
+func list(name string, children ...*Node) *Node {
+	return &Node{Name: name, Module: "m", Kind: KindList, Children: children}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modules  []*Module
+		warnings []string
+	}{
+		{
+			name: "types",
+			modules: []*Module{{
+				Name: "m",
+				Identities: []Identity{
+					{Name: "ethernet", Module: "m"},
+				},
+				Nodes: []*Node{
+					container("system",
+						leaf("host-name", "string"),
+						&Node{Name: "contact", Module: "other", Kind: KindLeaf, Type: LeafType{Go: "string"}},
+						&Node{Name: "dns-server", Module: "m", Kind: KindLeafList, Type: LeafType{Go: "string"}},
+						&Node{Name: "debug", Module: "m", Kind: KindLeaf, Type: LeafType{Go: "bool", Empty: true}},
+						enum("mode", "auto", "manual-only"),
+						&Node{Name: "ntp", Module: "m", Kind: KindContainer, Presence: true, Children: []*Node{
+							leaf("enabled", "bool"),
+						}},
+					),
+					list("interface",
+						key("name", "string"),
+						key("unit", "uint32"),
+						leaf("mtu", "uint16"),
+					),
+				},
+			}},
+		},
+		{
+			name: "enum-key",
+			modules: []*Module{{
+				Name: "m",
+				Nodes: []*Node{
+					container("routing",
+						list("protocol",
+							&Node{Name: "type", Module: "m", Kind: KindLeaf, Key: true, Type: LeafType{Go: "string", Enums: []string{"static", "ospf"}}},
+							key("p", "string"),
+						),
+					),
+				},
+			}},
+		},
+		{
+			name: "collisions",
+			modules: []*Module{{
+				Name: "m",
+				Identities: []Identity{
+					{Name: "log", Module: "m"},
+				},
+				Nodes: []*Node{
+					leaf("top", "string"),
+					container("a"),
+					container("new-a-path"),
+					container("m-log"),
+					container("b",
+						leaf("foo-bar", "string"),
+						leaf("foo_bar", "string"),
+					),
+					list("log",
+						leaf("message", "string"),
+					),
+				},
+			}},
+			warnings: []string{
+				"top-level m:top is skipped, only containers and lists are generated",
+				"m:new-a-path is named NewAPath2, as NewAPath is used by m:a path constructor",
+				"m:m-log is named MLog2, as MLog is used by identity m:log",
+				"m:foo_bar is named FooBar2 in B, as FooBar is used by another child",
+				"list m:log has no keys, so Marshal can't address its entries and its path selects all of them",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			src, warnings, err := Generate("model", test.modules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(warnings, test.warnings) {
+				t.Errorf("warnings:\n%s\nwant:\n%s", strings.Join(warnings, "\n"), strings.Join(test.warnings, "\n"))
+			}
+
+			golden := filepath.Join("testdata", test.name+".golden")
+			if *update {
+				err = os.WriteFile(golden, src, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(src) != string(want) {
+				t.Errorf("output differs from %s:\n%s", golden, src)
+			}
+		})
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"oper-status": "OperStatus",
+		"ipv4":        "Ipv4",
+		"foo_bar.baz": "FooBarBaz",
+		"10g":         "N10g",
+		"-":           "N",
+	}
+	for name, want := range tests {
+		if got := goName(name); got != want {
+			t.Errorf("goName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParamName(t *testing.T) {
+	tests := map[string]string{
+		"Name":      "name",
+		"Type":      "typeKey",
+		"P":         "pKey",
+		"Fmt":       "fmtKey",
+		"Xpath":     "xpathKey",
+		"Interface": "interfaceKey",
+		"IfIndex":   "ifIndex",
+	}
+	for name, want := range tests {
+		if got := paramName(name); got != want {
+			t.Errorf("paramName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package gen
+
+// Kind is the kind of a data node.
+type Kind int
+
+const (
+	KindContainer Kind = iota
+	KindList
+	KindLeaf
+	KindLeafList
+)
+
+// LeafType is the resolved type of a leaf or leaf-list.
+type LeafType struct {
+	// Go is the Go type used for values, e.g. "uint32" or "string".
+	Go string
+	// Enums are the enumeration names, if the type is an enumeration.
+	Enums []string
+	// Empty is set for leaves of type empty.
+	Empty bool
+}
+
+// Node is a data node of a YANG module. Choices and cases are transparent,
+// their data nodes appear as children of the parent.
+type Node struct {
+	Name     string
+	Module   string
+	Kind     Kind
+	Presence bool
+	Key      bool
+	Type     LeafType
+	Children []*Node
+}
+
+type Identity struct {
+	Name   string
+	Module string
+}
+
+type Module struct {
+	Name       string
+	Nodes      []*Node
+	Identities []Identity
+}
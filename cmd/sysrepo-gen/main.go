@@ -0,0 +1,79 @@
+// Command sysrepo-gen generates Go types and path builders for YANG modules,
+// for use with sysrepo.Unmarshal, sysrepo.Marshal and the Session API.
+//
+// Usage:
+//
+//	sysrepo-gen [-o file] [-package name] -modules ietf-interfaces,ietf-system
+//	sysrepo-gen [-o file] [-package name] [-p searchdir] module.yang...
+//
+// With -modules, the modules are loaded from the context of a sysrepo
+// connection. Otherwise the given YANG files are parsed.
+//
+// The path builders quote key values with xpath.Quote. A value containing
+// both single and double quotes becomes a concat() expression, which sysrepo
+// only accepts in paths used for reading, not in edits.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattiaswal/go-sysrepo/cmd/sysrepo-gen/internal/gen"
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+func main() {
+	output := flag.String("o", "", "output `file`, standard output if empty")
+	pkg := flag.String("package", "model", "package `name` of the generated code")
+	modules := flag.String("modules", "", "comma separated `modules` to load from sysrepo")
+	searchDir := flag.String("p", "", "search `directory` for imports of YANG files")
+	flag.Parse()
+
+	schemas, err := load(*modules, *searchDir, flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sysrepo-gen:", err)
+		os.Exit(1)
+	}
+
+	src, warnings, err := gen.Generate(*pkg, schemas)
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, "sysrepo-gen: warning:", warning)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sysrepo-gen:", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	err = os.WriteFile(*output, src, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sysrepo-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func load(modules string, searchDir string, files []string) ([]*gen.Module, error) {
+	if modules == "" {
+		if len(files) == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		return loadFiles(searchDir, files)
+	}
+
+	conn, err := sysrepo.Connect(sysrepo.ConnDefault)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx := conn.AcquireContext()
+	defer conn.ReleaseContext()
+
+	return loadModules(ctx, strings.Split(modules, ","))
+}
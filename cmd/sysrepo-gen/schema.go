@@ -0,0 +1,171 @@
+package main
+
+/*
+ #cgo LDFLAGS: -lyang
+ #include <stdlib.h>
+ #include <libyang/libyang.h>
+
+ static uint64_t array_count(const void *array) {
+     return LY_ARRAY_COUNT(array);
+ }
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/mattiaswal/go-sysrepo/cmd/sysrepo-gen/internal/gen"
+)
+
+// loadModules converts the compiled schema of the named modules in a libyang
+// context into the generator model.
+func loadModules(ctx unsafe.Pointer, names []string) ([]*gen.Module, error) {
+	lyCtx := (*C.struct_ly_ctx)(ctx)
+
+	var result []*gen.Module
+	for _, name := range names {
+		nameC := C.CString(name)
+		mod := C.ly_ctx_get_module_implemented(lyCtx, nameC)
+		C.free(unsafe.Pointer(nameC))
+		if mod == nil {
+			return nil, fmt.Errorf("module %q is not implemented", name)
+		}
+
+		result = append(result, convertModule(mod))
+	}
+	return result, nil
+}
+
+// loadFiles parses YANG files into a new libyang context, searching imports in
+// searchDir, and converts them into the generator model.
+func loadFiles(searchDir string, files []string) ([]*gen.Module, error) {
+	var searchDirC *C.char
+	if searchDir != "" {
+		searchDirC = C.CString(searchDir)
+		defer C.free(unsafe.Pointer(searchDirC))
+	}
+
+	var ctx *C.struct_ly_ctx
+	if C.ly_ctx_new(searchDirC, 0, &ctx) != C.LY_SUCCESS {
+		return nil, fmt.Errorf("couldn't create libyang context")
+	}
+	defer C.ly_ctx_destroy(ctx)
+
+	var result []*gen.Module
+	for _, file := range files {
+		fileC := C.CString(file)
+		var mod *C.struct_lys_module
+		lyrc := C.lys_parse_path(ctx, fileC, C.LYS_IN_YANG, &mod)
+		C.free(unsafe.Pointer(fileC))
+		if lyrc != C.LY_SUCCESS {
+			return nil, fmt.Errorf("couldn't parse %s", file)
+		}
+
+		result = append(result, convertModule(mod))
+	}
+	return result, nil
+}
+
+func convertModule(mod *C.struct_lys_module) *gen.Module {
+	result := &gen.Module{
+		Name: C.GoString(mod.name),
+	}
+
+	if mod.compiled != nil {
+		result.Nodes = convertChildren(nil, mod.compiled)
+	}
+
+	count := int(C.array_count(unsafe.Pointer(mod.identities)))
+	for _, ident := range unsafe.Slice(mod.identities, count) {
+		result.Identities = append(result.Identities, gen.Identity{
+			Name:   C.GoString(ident.name),
+			Module: result.Name,
+		})
+	}
+
+	return result
+}
+
+func convertChildren(parent *C.struct_lysc_node, mod *C.struct_lysc_module) []*gen.Node {
+	var result []*gen.Node
+	for node := C.lys_getnext(nil, parent, mod, 0); node != nil; node = C.lys_getnext(node, parent, mod, 0) {
+		converted := convertNode(node)
+		if converted != nil {
+			result = append(result, converted)
+		}
+	}
+	return result
+}
+
+func convertNode(node *C.struct_lysc_node) *gen.Node {
+	result := &gen.Node{
+		Name:   C.GoString(node.name),
+		Module: C.GoString(node.module.name),
+	}
+
+	switch node.nodetype {
+	case C.LYS_CONTAINER:
+		result.Kind = gen.KindContainer
+		result.Presence = node.flags&C.LYS_PRESENCE != 0
+		result.Children = convertChildren(node, nil)
+	case C.LYS_LIST:
+		result.Kind = gen.KindList
+		result.Children = convertChildren(node, nil)
+	case C.LYS_LEAF:
+		leaf := (*C.struct_lysc_node_leaf)(unsafe.Pointer(node))
+		result.Kind = gen.KindLeaf
+		result.Key = node.flags&C.LYS_KEY != 0
+		result.Type = convertType(leaf._type)
+	case C.LYS_LEAFLIST:
+		leafList := (*C.struct_lysc_node_leaflist)(unsafe.Pointer(node))
+		result.Kind = gen.KindLeafList
+		result.Type = convertType(leafList._type)
+	default:
+		return nil
+	}
+
+	return result
+}
+
+func convertType(t *C.struct_lysc_type) gen.LeafType {
+	switch t.basetype {
+	case C.LY_TYPE_BOOL:
+		return gen.LeafType{Go: "bool"}
+	case C.LY_TYPE_EMPTY:
+		return gen.LeafType{Go: "bool", Empty: true}
+	case C.LY_TYPE_INT8:
+		return gen.LeafType{Go: "int8"}
+	case C.LY_TYPE_INT16:
+		return gen.LeafType{Go: "int16"}
+	case C.LY_TYPE_INT32:
+		return gen.LeafType{Go: "int32"}
+	case C.LY_TYPE_INT64:
+		return gen.LeafType{Go: "int64"}
+	case C.LY_TYPE_UINT8:
+		return gen.LeafType{Go: "uint8"}
+	case C.LY_TYPE_UINT16:
+		return gen.LeafType{Go: "uint16"}
+	case C.LY_TYPE_UINT32:
+		return gen.LeafType{Go: "uint32"}
+	case C.LY_TYPE_UINT64:
+		return gen.LeafType{Go: "uint64"}
+	case C.LY_TYPE_DEC64:
+		return gen.LeafType{Go: "float64"}
+	case C.LY_TYPE_LEAFREF:
+		leafref := (*C.struct_lysc_type_leafref)(unsafe.Pointer(t))
+		return convertType(leafref.realtype)
+	case C.LY_TYPE_ENUM:
+		enum := (*C.struct_lysc_type_enum)(unsafe.Pointer(t))
+		count := int(C.array_count(unsafe.Pointer(enum.enums)))
+
+		result := gen.LeafType{Go: "string"}
+		for _, item := range unsafe.Slice(enum.enums, count) {
+			result.Enums = append(result.Enums, C.GoString(item.name))
+		}
+		return result
+	default:
+		// Strings, identityrefs, instance-identifiers, bits, binary and
+		// unions are all represented by their canonical string value.
+		return gen.LeafType{Go: "string"}
+	}
+}
@@ -22,6 +22,7 @@ func main() {
 	defer sess.Close()
 
 	getData(sess)
+	getInto(sess)
 	getItem(sess)
 }
 
@@ -42,12 +43,35 @@ func getData(sess *sysrepo.Session) {
 	for node := sources.Child(); node.Ptr != nil; node = node.Next() {
 		poll := node.ChildValue("poll")
 		address := node.ChildValue("address")
-		fmt.Println("Addess: " + address + " Poll: " + poll)
+		fmt.Println("Address: " + address + " Poll: " + poll)
 		fmt.Println("--------------------------")
 	}
 	fmt.Println("=============================")
 }
 
+type ntpSources struct {
+	Sources []struct {
+		Address string `yang:"address,key"`
+		Poll    string `yang:"poll"`
+	} `yang:"source"`
+}
+
+func getInto(sess *sysrepo.Session) {
+	fmt.Println("Example: Get data from sysrepo into a struct")
+
+	var sources ntpSources
+	err := sess.GetInto("/ietf-system:system-state/ntp/sources", &sources)
+	if err != nil {
+		println("Error Getting NTP sources")
+		return
+	}
+
+	for _, source := range sources.Sources {
+		fmt.Println("Address: " + source.Address + " Poll: " + source.Poll)
+	}
+	fmt.Println("=============================")
+}
+
 func getItem(sess *sysrepo.Session) {
 	fmt.Println("GetItem from sysrepo")
 	hostname, err := sess.GetItem("/ietf-system:system/hostname")
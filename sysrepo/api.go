@@ -0,0 +1,286 @@
+package sysrepo
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepoapi"
+)
+
+// API returns the connection as a sysrepoapi.Connection, for code that is
+// written against the interfaces so it can also run on sysrepofake.
+func (c *Connection) API() sysrepoapi.Connection {
+	return connectionAPI{conn: c}
+}
+
+// API returns the session as a sysrepoapi.Session.
+func (s *Session) API() sysrepoapi.Session {
+	return sessionAPI{session: s}
+}
+
+type connectionAPI struct {
+	conn *Connection
+}
+
+func (c connectionAPI) SessionStart(datastore sysrepoapi.Datastore) (sysrepoapi.Session, error) {
+	ds, err := datastoreFromAPI(datastore)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := c.conn.SessionStart(ds)
+	if err != nil {
+		return nil, errorToAPI(err)
+	}
+	return sessionAPI{session: session}, nil
+}
+
+func (c connectionAPI) Close() {
+	c.conn.Close()
+}
+
+type sessionAPI struct {
+	session *Session
+}
+
+func (s sessionAPI) ActiveDatastore() sysrepoapi.Datastore {
+	switch s.session.ActiveDatastore() {
+	case DSCandidate:
+		return sysrepoapi.DSCandidate
+	case DSStartup:
+		return sysrepoapi.DSStartup
+	case DSOperational:
+		return sysrepoapi.DSOperational
+	case DSFactoryDefault:
+		return sysrepoapi.DSFactoryDefault
+	default:
+		return sysrepoapi.DSRunning
+	}
+}
+
+func (s sessionAPI) SwitchDatastore(datastore sysrepoapi.Datastore) error {
+	ds, err := datastoreFromAPI(datastore)
+	if err != nil {
+		return err
+	}
+	return errorToAPI(s.session.SwitchDatastore(ds))
+}
+
+func (s sessionAPI) GetItem(path string) (string, error) {
+	value, err := s.session.GetItem(path)
+	return value, errorToAPI(err)
+}
+
+func (s sessionAPI) SetItem(path string, value *string, opts sysrepoapi.EditOptions) error {
+	return errorToAPI(s.session.SetItem(path, value, editOptionsFromAPI(opts)))
+}
+
+func (s sessionAPI) DeleteItem(path string, opts sysrepoapi.EditOptions) error {
+	return errorToAPI(s.session.DeleteItem(path, editOptionsFromAPI(opts)))
+}
+
+func (s sessionAPI) MoveItem(path string, position sysrepoapi.MovePosition, keysOrValue *string, origin *string, opts sysrepoapi.EditOptions) error {
+	var pos MovePosition
+	switch position {
+	case sysrepoapi.MoveBefore:
+		pos = MoveBefore
+	case sysrepoapi.MoveAfter:
+		pos = MoveAfter
+	case sysrepoapi.MoveFirst:
+		pos = MoveFirst
+	case sysrepoapi.MoveLast:
+		pos = MoveLast
+	default:
+		return sysrepoapi.Error{
+			Message: fmt.Sprintf("Unknown move position %d", position),
+			Code:    sysrepoapi.ErrInvalArg,
+		}
+	}
+	return errorToAPI(s.session.MoveItem(path, pos, keysOrValue, origin, editOptionsFromAPI(opts)))
+}
+
+func (s sessionAPI) ApplyChanges(timeout time.Duration) error {
+	return errorToAPI(s.session.ApplyChanges(timeout))
+}
+
+func (s sessionAPI) DiscardChanges(xpath *string) error {
+	return errorToAPI(s.session.DiscardChanges(xpath))
+}
+
+func (s sessionAPI) ModuleChangeSubscribe(moduleName string, xpath *string, callback sysrepoapi.ModuleChangeCallback, priority uint32, opts sysrepoapi.SubscribeOptions) (sysrepoapi.Subscription, error) {
+	var subOpts SubscribeOptions
+	if opts&sysrepoapi.SubsDoneOnly != 0 {
+		subOpts |= SubsDoneOnly
+	}
+	if opts&sysrepoapi.SubsEnabled != 0 {
+		subOpts |= SubsEnabled
+	}
+	if opts&sysrepoapi.SubsUpdate != 0 {
+		subOpts |= SubsUpdate
+	}
+
+	changesXpath := "/" + moduleName + ":*//."
+	if xpath != nil {
+		changesXpath = *xpath + "//."
+	}
+
+	sub, err := s.session.ModuleChangeSubscribe(moduleName, xpath, func(session *Session, subID uint32, moduleName string, xpath *string, event Event, requestID uint32) error {
+		changes, err := changesToAPI(session, changesXpath)
+		if err != nil {
+			return err
+		}
+
+		err = callback(sessionAPI{session: session}, moduleName, xpath, eventToAPI(event), changes)
+		var apiErr sysrepoapi.Error
+		if errors.As(err, &apiErr) {
+			return Error{Message: apiErr.Message, Code: errorCodeFromAPI(apiErr.Code)}
+		}
+		return err
+	}, priority, subOpts)
+	if err != nil {
+		return nil, errorToAPI(err)
+	}
+	return sub, nil
+}
+
+func (s sessionAPI) Close() {
+	s.session.Close()
+}
+
+// changesToAPI reads all changes under xpath of an event session.
+func changesToAPI(session *Session, xpath string) ([]sysrepoapi.Change, error) {
+	iter, err := session.GetChanges(xpath).Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var changes []sysrepoapi.Change
+	for ; iter.HasNext(); err = iter.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		change := iter.Current()
+		changes = append(changes, sysrepoapi.Change{
+			Operation:       changeOperationToAPI(change.Operation),
+			Path:            change.Path,
+			Value:           change.Value,
+			PreviousValue:   change.PreviousValue,
+			PreviousList:    change.PreviousList,
+			PreviousDefault: change.PreviousDefault,
+		})
+	}
+	return changes, nil
+}
+
+func datastoreFromAPI(datastore sysrepoapi.Datastore) (Datastore, error) {
+	switch datastore {
+	case sysrepoapi.DSRunning:
+		return DSRunning, nil
+	case sysrepoapi.DSCandidate:
+		return DSCandidate, nil
+	case sysrepoapi.DSStartup:
+		return DSStartup, nil
+	case sysrepoapi.DSOperational:
+		return DSOperational, nil
+	case sysrepoapi.DSFactoryDefault:
+		return DSFactoryDefault, nil
+	}
+	return 0, sysrepoapi.Error{
+		Message: fmt.Sprintf("Unknown datastore %d", datastore),
+		Code:    sysrepoapi.ErrInvalArg,
+	}
+}
+
+func editOptionsFromAPI(opts sysrepoapi.EditOptions) EditOptions {
+	result := EditDefault
+	if opts&sysrepoapi.EditNonRecursive != 0 {
+		result |= EditNonRecursive
+	}
+	if opts&sysrepoapi.EditStrict != 0 {
+		result |= EditStrict
+	}
+	if opts&sysrepoapi.EditIsolate != 0 {
+		result |= EditIsolate
+	}
+	return result
+}
+
+func eventToAPI(event Event) sysrepoapi.Event {
+	switch event {
+	case EvDone:
+		return sysrepoapi.EvDone
+	case EvAbort:
+		return sysrepoapi.EvAbort
+	case EvEnabled:
+		return sysrepoapi.EvEnabled
+	case EvUpdate:
+		return sysrepoapi.EvUpdate
+	default:
+		return sysrepoapi.EvChange
+	}
+}
+
+func changeOperationToAPI(op ChangeOperation) sysrepoapi.ChangeOperation {
+	switch op {
+	case OpModified:
+		return sysrepoapi.OpModified
+	case OpDeleted:
+		return sysrepoapi.OpDeleted
+	case OpMoved:
+		return sysrepoapi.OpMoved
+	default:
+		return sysrepoapi.OpCreated
+	}
+}
+
+var apiErrorCodes = map[ErrorCode]sysrepoapi.ErrorCode{
+	ErrOk:               sysrepoapi.ErrOk,
+	ErrInvalArg:         sysrepoapi.ErrInvalArg,
+	ErrLibyang:          sysrepoapi.ErrLibyang,
+	ErrSyscallFailed:    sysrepoapi.ErrSyscallFailed,
+	ErrNoMemory:         sysrepoapi.ErrNoMemory,
+	ErrNotFound:         sysrepoapi.ErrNotFound,
+	ErrExists:           sysrepoapi.ErrExists,
+	ErrInternal:         sysrepoapi.ErrInternal,
+	ErrUnsupported:      sysrepoapi.ErrUnsupported,
+	ErrValidationFailed: sysrepoapi.ErrValidationFailed,
+	ErrOperationFailed:  sysrepoapi.ErrOperationFailed,
+	ErrUnauthorized:     sysrepoapi.ErrUnauthorized,
+	ErrLocked:           sysrepoapi.ErrLocked,
+	ErrTimeout:          sysrepoapi.ErrTimeout,
+	ErrCallbackFailed:   sysrepoapi.ErrCallbackFailed,
+	ErrCallbackShelve:   sysrepoapi.ErrCallbackShelve,
+}
+
+// errorToAPI converts an error holding an Error into a sysrepoapi.Error with
+// the same code, keeping the messages of wrapped or joined errors. Other
+// errors are returned unchanged.
+func errorToAPI(err error) error {
+	var srErr Error
+	if !errors.As(err, &srErr) {
+		return err
+	}
+
+	code, ok := apiErrorCodes[srErr.Code]
+	if !ok {
+		code = sysrepoapi.ErrInternal
+	}
+
+	message := srErr.Message
+	if err != error(srErr) {
+		message = err.Error()
+	}
+	return sysrepoapi.Error{Message: message, Code: code}
+}
+
+func errorCodeFromAPI(code sysrepoapi.ErrorCode) ErrorCode {
+	for srCode, apiCode := range apiErrorCodes {
+		if apiCode == code {
+			return srCode
+		}
+	}
+	return ErrCallbackFailed
+}
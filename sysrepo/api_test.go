@@ -0,0 +1,32 @@
+package sysrepo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepoapi"
+)
+
+func TestErrorToAPI(t *testing.T) {
+	locked := Error{Message: "Couldn't lock", Code: ErrLocked}
+	switchBack := Error{Message: "Couldn't switch back", Code: ErrInternal}
+	plain := errors.New("plain")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"error", locked, sysrepoapi.Error{Message: "Couldn't lock", Code: sysrepoapi.ErrLocked}},
+		{"wrapped", fmt.Errorf("unlocking: %w", locked), sysrepoapi.Error{Message: "unlocking: " + locked.Error(), Code: sysrepoapi.ErrLocked}},
+		{"joined", errors.Join(locked, switchBack), sysrepoapi.Error{Message: locked.Error() + "\n" + switchBack.Error(), Code: sysrepoapi.ErrLocked}},
+		{"other", plain, plain},
+	}
+
+	for _, test := range tests {
+		if got := errorToAPI(test.err); got != test.want {
+			t.Errorf("%s: errorToAPI() = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
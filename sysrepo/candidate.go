@@ -0,0 +1,257 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Candidate implements a NETCONF-style commit workflow on top of the
+// candidate datastore. Edits are made to the candidate and only reach running
+// on Commit.
+type Candidate struct {
+	mu      sync.Mutex
+	session *Session
+	pending *confirmedCommit
+	// generation identifies the latest confirmation timer, so that a timer
+	// which fired while being restarted does not roll back.
+	generation uint64
+	// expireErr is the error of the last automatic rollback, returned by the
+	// next Confirm, CancelCommit or Close.
+	expireErr error
+}
+
+type confirmedCommit struct {
+	timer   *time.Timer
+	backup  string
+	timeout time.Duration
+}
+
+// NewCandidate starts a new session on the candidate datastore.
+func NewCandidate(conn *Connection) (*Candidate, error) {
+	session, err := conn.SessionStart(DSCandidate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Candidate{session: session}, nil
+}
+
+// Close rolls back a pending confirmed commit and stops the session.
+func (c *Candidate) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.takeExpireErr()
+	if c.pending != nil {
+		err = errors.Join(err, c.rollback())
+	}
+	c.session.Close()
+	return err
+}
+
+func (c *Candidate) SetItem(path string, value *string, opts EditOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.session.SetItem(path, value, opts)
+}
+
+func (c *Candidate) DeleteItem(path string, opts EditOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.session.DeleteItem(path, opts)
+}
+
+func (c *Candidate) MoveItem(path string, position MovePosition, keysOrValue *string, origin *string, opts EditOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.session.MoveItem(path, position, keysOrValue, origin, opts)
+}
+
+// Validate validates the candidate datastore with the pending edits applied,
+// without changing it. A nil moduleName validates all modules.
+func (c *Candidate) Validate(moduleName *string, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var moduleNameC *C.char
+	var free func()
+	if moduleName != nil {
+		moduleNameC, free = stringToC(*moduleName)
+		defer free()
+	}
+
+	rc := C.sr_validate(c.session.sess, moduleNameC, C.uint(timeout/time.Millisecond))
+	return throwIfError(rc, "Candidate validation failed")
+}
+
+// Commit applies the pending edits to the candidate and copies it to running.
+// It also confirms a pending confirmed commit.
+func (c *Candidate) Commit(timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.commit(timeout)
+	if err != nil {
+		return err
+	}
+
+	c.confirm()
+	return nil
+}
+
+// ConfirmedCommit commits like Commit, but rolls running back to its previous
+// content unless Confirm or Commit is called within confirmTimeout. Issuing it
+// again while a confirmed commit is pending restarts the timer and keeps the
+// original rollback point.
+func (c *Candidate) ConfirmedCommit(confirmTimeout time.Duration, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := c.pending
+	if pending == nil {
+		backup, err := c.exportRunning(timeout)
+		if err != nil {
+			return err
+		}
+		pending = &confirmedCommit{backup: backup}
+	}
+
+	err := c.commit(timeout)
+	if err != nil {
+		return err
+	}
+
+	if c.pending != nil {
+		c.pending.timer.Stop()
+	}
+	c.pending = pending
+	c.pending.timeout = timeout
+
+	c.generation++
+	generation := c.generation
+	c.pending.timer = time.AfterFunc(confirmTimeout, func() {
+		c.expire(generation)
+	})
+	return nil
+}
+
+// Confirm makes the changes of a pending confirmed commit permanent. If the
+// commit already expired, it returns the error of its rollback, if any.
+func (c *Candidate) Confirm() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil {
+		return errors.Join(errors.New("no confirmed commit is pending"), c.takeExpireErr())
+	}
+
+	c.confirm()
+	return nil
+}
+
+// CancelCommit immediately rolls back a pending confirmed commit. If the
+// commit already expired, it returns the error of its rollback, if any.
+func (c *Candidate) CancelCommit() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil {
+		return errors.Join(errors.New("no confirmed commit is pending"), c.takeExpireErr())
+	}
+
+	return c.rollback()
+}
+
+// Discard drops the pending edits and resets the candidate to the content of
+// running.
+func (c *Candidate) Discard(timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.session.DiscardChanges(nil)
+	if err != nil {
+		return err
+	}
+
+	return c.session.CopyConfig(DSRunning, nil, timeout)
+}
+
+func (c *Candidate) commit(timeout time.Duration) (err error) {
+	err = c.session.ApplyChanges(timeout)
+	if err != nil {
+		return errors.Join(err, c.session.DiscardChanges(nil))
+	}
+
+	err = c.session.SwitchDatastore(DSRunning)
+	if err != nil {
+		return err
+	}
+	defer c.switchBack(&err)
+
+	return c.session.CopyConfig(DSCandidate, nil, timeout)
+}
+
+func (c *Candidate) exportRunning(timeout time.Duration) (data string, err error) {
+	err = c.session.SwitchDatastore(DSRunning)
+	if err != nil {
+		return "", err
+	}
+	defer c.switchBack(&err)
+
+	return c.session.ExportData("/*", FormatXML, GetNoFilter, timeout)
+}
+
+// switchBack returns the session to the candidate datastore after an
+// operation on running. A failed switch is joined to *err, as later edits
+// would reach running.
+func (c *Candidate) switchBack(err *error) {
+	*err = errors.Join(*err, c.session.SwitchDatastore(DSCandidate))
+}
+
+func (c *Candidate) confirm() {
+	if c.pending == nil {
+		return
+	}
+
+	c.pending.timer.Stop()
+	c.pending = nil
+}
+
+func (c *Candidate) rollback() (err error) {
+	pending := c.pending
+	pending.timer.Stop()
+	c.pending = nil
+
+	err = c.session.SwitchDatastore(DSRunning)
+	if err != nil {
+		return err
+	}
+	defer c.switchBack(&err)
+
+	return c.session.ReplaceConfig(nil, pending.backup, FormatXML, pending.timeout)
+}
+
+func (c *Candidate) expire(generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil || c.generation != generation {
+		return
+	}
+
+	c.expireErr = c.rollback()
+}
+
+func (c *Candidate) takeExpireErr() error {
+	err := c.expireErr
+	c.expireErr = nil
+	return err
+}
@@ -6,10 +6,15 @@ package sysrepo
 import "C"
 import (
 	"runtime"
+	"unsafe"
 )
 
 type Change struct {
-	Operation       ChangeOperation
+	Operation ChangeOperation
+	// Path and Value describe the changed node. Value is nil for nodes
+	// without a value.
+	Path            string
+	Value           *string
 	PreviousValue   *string
 	PreviousList    *string
 	PreviousDefault bool
@@ -115,8 +120,19 @@ func (i *ChangeIterator) Next() error {
 		goPrevList = &s
 	}
 
+	pathC := C.lyd_path(node, C.LYD_PATH_STD, nil, 0)
+	defer C.free(unsafe.Pointer(pathC))
+
+	var goValue *string
+	if node.schema != nil && node.schema.nodetype&(C.LYS_LEAF|C.LYS_LEAFLIST) != 0 {
+		s := C.GoString(C.lyd_get_value(node))
+		goValue = &s
+	}
+
 	i.current = &Change{
 		Operation:       ChangeOperation(operation),
+		Path:            C.GoString(pathC),
+		Value:           goValue,
 		PreviousValue:   goPrevValue,
 		PreviousList:    goPrevList,
 		PreviousDefault: prevDefault != 0,
@@ -5,12 +5,23 @@ package sysrepo
 // #include <sysrepo.h>
 import "C"
 import (
+	"context"
 	"runtime"
+	"sync"
 	"time"
+	"unsafe"
 )
 
+// schemaPollInterval is how often WatchSchemaChanges checks the content ID.
+const schemaPollInterval = time.Second
+
 type Connection struct {
 	conn *C.sr_conn_ctx_t
+
+	// mu keeps Close from freeing conn while a schema watcher uses it, and
+	// closed stops the watchers.
+	mu     sync.RWMutex
+	closed chan struct{}
 }
 
 type ConnectionFlag int
@@ -32,18 +43,33 @@ func Connect(options ConnectionFlag) (*Connection, error) {
 		}
 	}
 
-	connection := &Connection{conn: conn}
+	connection := &Connection{conn: conn, closed: make(chan struct{})}
 	runtime.SetFinalizer(connection, (*Connection).Close)
 	return connection, nil
 }
 
 func (c *Connection) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.conn != nil {
+		close(c.closed)
 		C.sr_disconnect(c.conn)
 		c.conn = nil
 	}
 }
 
+// AcquireContext returns the libyang context (struct ly_ctx *) of the
+// connection for use with libyang bindings. It must be released with
+// ReleaseContext.
+func (c *Connection) AcquireContext() unsafe.Pointer {
+	return unsafe.Pointer(C.sr_acquire_context(c.conn))
+}
+
+func (c *Connection) ReleaseContext() {
+	C.sr_release_context(c.conn)
+}
+
 func (c *Connection) SessionStart(datastore Datastore) (*Session, error) {
 	var sess *C.sr_session_ctx_t
 	rc := C.sr_session_start(c.conn, C.sr_datastore_t(datastore), &sess)
@@ -76,6 +102,105 @@ func (c *Connection) SetModuleReplaySupport(moduleName string, enabled bool) err
 	return throwIfError(rc, "Couldn't set replay support for module '"+moduleName+"'")
 }
 
+// InstallModules installs the YANG modules at schemaPaths with all their
+// features disabled. Imports are searched for in searchDirs, a list of
+// directories separated by ':', and in the directories of the schemas.
+func (c *Connection) InstallModules(schemaPaths []string, searchDirs *string) error {
+	paths := (**C.char)(C.calloc(C.size_t(len(schemaPaths)+1), C.size_t(unsafe.Sizeof((*C.char)(nil)))))
+	defer C.free(unsafe.Pointer(paths))
+
+	pathsC := unsafe.Slice(paths, len(schemaPaths)+1)
+	for i, path := range schemaPaths {
+		pathC, free := stringToC(path)
+		defer free()
+		pathsC[i] = pathC
+	}
+
+	var searchDirsC *C.char
+	if searchDirs != nil {
+		var free func()
+		searchDirsC, free = stringToC(*searchDirs)
+		defer free()
+	}
+
+	rc := C.sr_install_modules(c.conn, paths, searchDirsC, nil)
+	return throwIfError(rc, "Couldn't install modules")
+}
+
+// InstallModuleData sets the initial data of an installed module, used to
+// seed its startup, running and factory-default datastores.
+func (c *Connection) InstallModuleData(moduleName string, data string, format DataFormat) error {
+	moduleNameC, freeModule := stringToC(moduleName)
+	defer freeModule()
+
+	dataC, freeData := stringToC(data)
+	defer freeData()
+
+	rc := C.sr_install_module_data(c.conn, moduleNameC, dataC, nil, formatToC(format))
+	return throwIfError(rc, "Couldn't install data for module '"+moduleName+"'")
+}
+
+// ContentID returns the current content ID of the connection context. It
+// changes whenever modules are installed, removed, updated or their features
+// change.
+func (c *Connection) ContentID() uint32 {
+	return uint32(C.sr_get_content_id(c.conn))
+}
+
+// WatchSchemaChanges returns a channel receiving the new content ID every time
+// the schema changes. The channel is closed when ctx is done or the connection
+// is closed.
+func (c *Connection) WatchSchemaChanges(ctx context.Context) <-chan uint32 {
+	changes := make(chan uint32)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(schemaPollInterval)
+		defer ticker.Stop()
+
+		lastID, ok := c.watchedContentID()
+		for ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			case <-ticker.C:
+			}
+
+			var id uint32
+			id, ok = c.watchedContentID()
+			if !ok || id == lastID {
+				continue
+			}
+			lastID = id
+
+			select {
+			case changes <- id:
+			case <-ctx.Done():
+				return
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+
+	return changes
+}
+
+// watchedContentID returns the content ID, or false if the connection is
+// closed.
+func (c *Connection) watchedContentID() (uint32, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.conn == nil {
+		return 0, false
+	}
+	return c.ContentID(), true
+}
+
 type ModuleReplaySupport struct {
 	Enabled       bool
 	EarliestNotif *time.Time
@@ -0,0 +1,51 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+import (
+	"encoding/binary"
+)
+
+// netopeer2OriginatorName is the originator name used by netopeer2, which
+// pushes the NETCONF session ID as the first chunk of originator data.
+const netopeer2OriginatorName = "netopeer2"
+
+// The Event methods are only meaningful on the session passed to callbacks,
+// where they describe the session which originated the event.
+
+// EventSessionID returns the sysrepo session ID of the originator.
+func (s *Session) EventSessionID() uint32 {
+	return uint32(C.sr_session_get_event_sid(s.sess))
+}
+
+// EventUser returns the system user of the originator.
+func (s *Session) EventUser() string {
+	return C.GoString(C.sr_session_get_event_user(s.sess))
+}
+
+// EventNetconfID returns the NETCONF session ID of the originator, if it is
+// a NETCONF session of netopeer2.
+func (s *Session) EventNetconfID() (uint32, bool) {
+	if s.EventOriginatorName() != netopeer2OriginatorName {
+		return 0, false
+	}
+
+	data, err := s.OriginatorData(0)
+	if err != nil || len(data) != 4 {
+		return 0, false
+	}
+	return binary.NativeEndian.Uint32(data), true
+}
+
+// EventIsEnabled reports whether the event is the initial replay of the
+// current configuration requested by SubsEnabled.
+func (s *Session) EventIsEnabled() bool {
+	return C.sr_session_get_event_is_enabled(s.sess) != 0
+}
+
+// EventOriginatorName returns the originator name of the originator.
+func (s *Session) EventOriginatorName() string {
+	return s.GetOriginatorName()
+}
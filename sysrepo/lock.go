@@ -5,10 +5,17 @@ package sysrepo
 // #include <sysrepo.h>
 import "C"
 import (
+	"context"
+	"errors"
 	"runtime"
 	"time"
 )
 
+const (
+	lockBackoffMin = 10 * time.Millisecond
+	lockBackoffMax = time.Second
+)
+
 type Lock struct {
 	session  *Session
 	lockedDs Datastore
@@ -46,6 +53,37 @@ func NewLock(session *Session, moduleName *string, timeout *time.Duration) (*Loc
 	return lock, nil
 }
 
+// LockContext locks the active datastore like NewLock, but keeps retrying with
+// an increasing backoff while the lock is held by someone else, until ctx is
+// done.
+func (s *Session) LockContext(ctx context.Context, moduleName *string) (*Lock, error) {
+	backoff := lockBackoffMin
+	for {
+		lock, err := NewLock(s, moduleName, nil)
+		if err == nil {
+			return lock, nil
+		}
+
+		var srErr Error
+		if !errors.As(err, &srErr) || srErr.Code != ErrLocked {
+			return nil, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > lockBackoffMax {
+			backoff = lockBackoffMax
+		}
+	}
+}
+
 func (l *Lock) Unlock() error {
 	if l.session == nil {
 		return nil // Already unlocked
@@ -67,15 +105,84 @@ func (l *Lock) Unlock() error {
 
 	rc := C.sr_unlock(l.session.sess, moduleNameC)
 
-	l.session.SwitchDatastore(currentDs)
+	switchErr := l.session.SwitchDatastore(currentDs)
 
 	if rc != C.SR_ERR_OK {
-		return Error{
+		return errors.Join(Error{
 			Message: "Cannot unlock session",
 			Code:    ErrorCode(rc),
-		}
+		}, switchErr)
 	}
 
 	l.session = nil // Mark as unlocked
-	return nil
+	runtime.SetFinalizer(l, nil)
+	return switchErr
+}
+
+// WithLock locks the datastore ds, waiting for it as LockContext does, and
+// runs fn with the session switched to ds. The lock is always released and
+// the original datastore restored before returning; any errors from doing so
+// are joined with the error of fn.
+func (s *Session) WithLock(ctx context.Context, ds Datastore, moduleName *string, fn func(*Session) error) (err error) {
+	currentDs := s.ActiveDatastore()
+
+	err = s.SwitchDatastore(ds)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, s.SwitchDatastore(currentDs))
+	}()
+
+	lock, err := s.LockContext(ctx, moduleName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, lock.Unlock())
+	}()
+
+	return fn(s)
+}
+
+// LockStatus describes the lock of a datastore as reported by sysrepo.
+// Sysrepo does not record the NETCONF session ID of the holder, only its
+// sysrepo session ID.
+type LockStatus struct {
+	Locked    bool
+	SessionID uint32
+	Timestamp time.Time
+}
+
+// LockInfo returns who, if anyone, holds the lock of the datastore ds. A nil
+// moduleName checks the lock of the whole datastore.
+func (s *Session) LockInfo(ds Datastore, moduleName *string) (*LockStatus, error) {
+	var moduleNameC *C.char
+	var free func()
+	if moduleName != nil {
+		moduleNameC, free = stringToC(*moduleName)
+		defer free()
+	}
+
+	var isLocked C.int
+	var id C.uint32_t
+	var timestamp C.struct_timespec
+
+	rc := C.sr_get_lock(s.conn.conn, C.sr_datastore_t(ds), moduleNameC, &isLocked, &id, &timestamp)
+	if rc != C.SR_ERR_OK {
+		return nil, Error{
+			Message: "Couldn't get lock information",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	status := &LockStatus{
+		Locked: isLocked != 0,
+	}
+	if status.Locked {
+		status.SessionID = uint32(id)
+		status.Timestamp = time.Unix(int64(timestamp.tv_sec), int64(timestamp.tv_nsec))
+	}
+
+	return status, nil
 }
@@ -0,0 +1,87 @@
+package sysrepo
+
+/*
+ #cgo LDFLAGS: -lsysrepo
+ #include <stdlib.h>
+ #include <sysrepo.h>
+
+ extern void goLogCallback(sr_log_level_t level, char *message);
+*/
+import "C"
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"unsafe"
+)
+
+type LogLevel int
+
+const (
+	LogNone    LogLevel = C.SR_LL_NONE
+	LogError   LogLevel = C.SR_LL_ERR
+	LogWarning LogLevel = C.SR_LL_WRN
+	LogInfo    LogLevel = C.SR_LL_INF
+	LogDebug   LogLevel = C.SR_LL_DBG
+)
+
+var (
+	logger   atomic.Pointer[slog.Logger]
+	logLevel atomic.Int32
+)
+
+// SetLogger delivers sysrepo log messages up to level to logger. A nil logger
+// stops the delivery.
+func SetLogger(l *slog.Logger, level LogLevel) {
+	SetLogLevel(level)
+	logger.Store(l)
+
+	if l == nil {
+		C.sr_log_set_cb(nil)
+		return
+	}
+	C.sr_log_set_cb((C.sr_log_cb)(unsafe.Pointer(C.goLogCallback)))
+}
+
+// SetLogLevel changes the most verbose level delivered to the logger set by
+// SetLogger.
+func SetLogLevel(level LogLevel) {
+	logLevel.Store(int32(level))
+}
+
+// LogStderr enables printing sysrepo log messages up to level to stderr.
+func LogStderr(level LogLevel) {
+	C.sr_log_stderr(C.sr_log_level_t(level))
+}
+
+// LogSyslog enables sending sysrepo log messages up to level to syslog under
+// appName.
+func LogSyslog(appName string, level LogLevel) {
+	appNameC, free := stringToC(appName)
+	defer free()
+
+	C.sr_log_syslog(appNameC, C.sr_log_level_t(level))
+}
+
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case LogError:
+		return slog.LevelError
+	case LogWarning:
+		return slog.LevelWarn
+	case LogInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
+
+//export goLogCallback
+func goLogCallback(level C.sr_log_level_t, message *C.char) {
+	l := logger.Load()
+	if l == nil || LogLevel(level) > LogLevel(logLevel.Load()) {
+		return
+	}
+
+	l.Log(context.Background(), LogLevel(level).slogLevel(), C.GoString(message))
+}
@@ -0,0 +1,431 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/xpath"
+)
+
+// Edit is a single change produced by Marshal. Path is relative to the node
+// the marshalled value represents and Value is nil for nodes without a value,
+// such as containers, list entries and empty leaves.
+type Edit struct {
+	Path  string
+	Value *string
+}
+
+// fieldTag is a parsed `yang:"name,opt,..."` struct tag. The options are:
+//
+//	key        the leaf is a key of the list entry the struct represents
+//	omitempty  zero values are not marshalled
+//	empty      the bool field represents a leaf of type empty
+type fieldTag struct {
+	name      string
+	key       bool
+	omitempty bool
+	empty     bool
+}
+
+func parseFieldTag(field reflect.StructField) (fieldTag, bool) {
+	tag, ok := field.Tag.Lookup("yang")
+	if !ok || tag == "-" || !field.IsExported() {
+		return fieldTag{}, false
+	}
+
+	parts := strings.Split(tag, ",")
+	result := fieldTag{name: parts[0]}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "key":
+			result.key = true
+		case "omitempty":
+			result.omitempty = true
+		case "empty":
+			result.empty = true
+		}
+	}
+	return result, result.name != ""
+}
+
+// Unmarshal fills v, which must be a pointer, from the data node tree. A
+// struct is filled from the children of the node: containers map to structs,
+// presence containers and optional leaves to pointers, lists to slices of
+// structs or to maps keyed by the list key, and leaf-lists to slices.
+// Fields without a yang tag are ignored.
+func Unmarshal(tree libyang.DataNode, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("unmarshal target must be a non-nil pointer")
+	}
+
+	node := nodeToC(tree)
+	if node == nil {
+		return Error{
+			Message: "Nothing to unmarshal",
+			Code:    ErrNotFound,
+		}
+	}
+
+	return unmarshalValue(node, rv.Elem(), fieldTag{})
+}
+
+func unmarshalValue(node *C.struct_lyd_node, v reflect.Value, tag fieldTag) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		err := unmarshalValue(node, p.Elem(), tag)
+		if err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	case reflect.Struct:
+		return unmarshalChildren(node, v)
+	default:
+		return setScalar(v, C.GoString(C.lyd_get_value(node)), tag)
+	}
+}
+
+func unmarshalChildren(node *C.struct_lyd_node, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := parseFieldTag(t.Field(i))
+		if !ok {
+			continue
+		}
+
+		err := unmarshalField(findChildren(node, tag.name), v.Field(i), tag)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tag.name, err)
+		}
+	}
+	return nil
+}
+
+func unmarshalField(nodes []*C.struct_lyd_node, v reflect.Value, tag fieldTag) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if len(nodes) == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(nodes), len(nodes))
+		for i, node := range nodes {
+			err := unmarshalValue(node, s.Index(i), tag)
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMapWithSize(v.Type(), len(nodes))
+		for _, node := range nodes {
+			entry := reflect.New(v.Type().Elem()).Elem()
+			err := unmarshalValue(node, entry, tag)
+			if err != nil {
+				return err
+			}
+
+			key, err := mapKey(reflect.Indirect(entry), v.Type().Key())
+			if err != nil {
+				return err
+			}
+			m.SetMapIndex(key, entry)
+		}
+		v.Set(m)
+	default:
+		if len(nodes) == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return unmarshalValue(nodes[0], v, tag)
+	}
+	return nil
+}
+
+// findChildren returns the children of node called name, which may be
+// prefixed with the module name.
+func findChildren(node *C.struct_lyd_node, name string) []*C.struct_lyd_node {
+	module, name, prefixed := strings.Cut(name, ":")
+	if !prefixed {
+		name, module = module, ""
+	}
+
+	var result []*C.struct_lyd_node
+	for child := C.lyd_child(node); child != nil; child = child.next {
+		if child.schema == nil || C.GoString(child.schema.name) != name {
+			continue
+		}
+		if module != "" && C.GoString(child.schema.module.name) != module {
+			continue
+		}
+		result = append(result, child)
+	}
+	return result
+}
+
+// mapKey returns the value of the key field of a list entry struct as a map
+// key. Only lists with a single key can be unmarshalled into maps.
+func mapKey(entry reflect.Value, keyType reflect.Type) (reflect.Value, error) {
+	fields := keyFields(entry)
+	if len(fields) != 1 {
+		return reflect.Value{}, errors.New("map list entries must have exactly one key field")
+	}
+
+	key := entry.Field(fields[0])
+	if !key.Type().ConvertibleTo(keyType) {
+		return reflect.Value{}, fmt.Errorf("key of type %s can't be used as map key of type %s", key.Type(), keyType)
+	}
+	return key.Convert(keyType), nil
+}
+
+func keyFields(entry reflect.Value) []int {
+	var result []int
+	for i := 0; i < entry.NumField(); i++ {
+		tag, ok := parseFieldTag(entry.Type().Field(i))
+		if ok && tag.key {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+func setScalar(v reflect.Value, value string, tag fieldTag) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		if tag.empty {
+			v.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Marshal converts v, a struct or a pointer to one, into the edits creating
+// its data. It uses the same yang tags as Unmarshal; key fields of list
+// entries become predicates of their paths.
+func Marshal(v any) ([]Edit, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("marshal source must be a struct")
+	}
+
+	var edits []Edit
+	err := marshalStruct("", rv, &edits)
+	return edits, err
+}
+
+func marshalStruct(path string, v reflect.Value, edits *[]Edit) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := parseFieldTag(t.Field(i))
+		if !ok || tag.key {
+			continue
+		}
+
+		err := marshalField(path+"/"+tag.name, v.Field(i), tag, edits)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tag.name, err)
+		}
+	}
+	return nil
+}
+
+func marshalField(path string, v reflect.Value, tag fieldTag, edits *[]Edit) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			*edits = append(*edits, Edit{Path: path})
+		}
+		tag.omitempty = false
+		return marshalField(path, v.Elem(), tag, edits)
+	case reflect.Struct:
+		return marshalStruct(path, v, edits)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			err := marshalEntry(path, reflect.Indirect(v.Index(i)), reflect.Value{}, tag, edits)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, key := range keys {
+			err := marshalEntry(path, reflect.Indirect(v.MapIndex(key)), key, tag, edits)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		if tag.omitempty && v.IsZero() {
+			return nil
+		}
+		if tag.empty {
+			if v.Bool() {
+				*edits = append(*edits, Edit{Path: path})
+			}
+			return nil
+		}
+
+		value, err := formatScalar(v)
+		if err != nil {
+			return err
+		}
+		*edits = append(*edits, Edit{Path: path, Value: &value})
+	}
+	return nil
+}
+
+// marshalEntry marshals a list entry, or a leaf-list value if entry is not a
+// struct. The map key, if valid, is used for an unset key field.
+func marshalEntry(path string, entry reflect.Value, mapKey reflect.Value, tag fieldTag, edits *[]Edit) error {
+	if entry.Kind() != reflect.Struct {
+		value, err := formatScalar(entry)
+		if err != nil {
+			return err
+		}
+		*edits = append(*edits, Edit{Path: path, Value: &value})
+		return nil
+	}
+
+	fields := keyFields(entry)
+	if len(fields) == 0 {
+		return errors.New("list entries must have key fields")
+	}
+
+	for _, i := range fields {
+		key := entry.Field(i)
+		if key.IsZero() && mapKey.IsValid() && len(fields) == 1 {
+			key = mapKey
+		}
+
+		value, err := formatScalar(key)
+		if err != nil {
+			return err
+		}
+		keyTag, _ := parseFieldTag(entry.Type().Field(i))
+		quoted, err := xpath.QuoteLiteral(value)
+		if err != nil {
+			return Error{
+				Message: "Couldn't use the key " + keyTag.name + " of " + path + ": " + err.Error(),
+				Code:    ErrInvalArg,
+			}
+		}
+		path += "[" + keyTag.name + "=" + quoted + "]"
+	}
+
+	*edits = append(*edits, Edit{Path: path})
+	return marshalStruct(path, entry, edits)
+}
+
+func formatScalar(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+// GetInto reads the node at xpath and unmarshals it into v.
+func (s *Session) GetInto(xpath string, v any) error {
+	xpathC, freeXpath := stringToC(xpath)
+	defer freeXpath()
+
+	var data *C.sr_data_t
+	rc := C.sr_get_data(s.sess, xpathC, 0, 0, 0, &data)
+	if rc != C.SR_ERR_OK {
+		return Error{
+			Message: "Couldn't get '" + xpath + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+	if data == nil {
+		return Error{
+			Message: "No data at '" + xpath + "'",
+			Code:    ErrNotFound,
+		}
+	}
+	defer C.sr_release_data(data)
+
+	var node *C.struct_lyd_node
+	lyrc := C.lyd_find_path(data.tree, xpathC, 0, &node)
+	if lyrc != C.LY_SUCCESS {
+		return Error{
+			Message: "No data at '" + xpath + "'",
+			Code:    ErrNotFound,
+		}
+	}
+
+	return Unmarshal(libyang.NewNode(unsafe.Pointer(node)), v)
+}
+
+// SetFrom prepares the edits creating the data of v at xpath. The changes are
+// stored in the session until applied with ApplyChanges.
+func (s *Session) SetFrom(xpath string, v any) error {
+	edits, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	for _, edit := range edits {
+		err = s.SetItem(xpath+edit.Path, edit.Value, EditDefault)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package sysrepo_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepotest"
+)
+
+type testSystem struct {
+	Hostname   *string    `yang:"hostname"`
+	DNSServers []string   `yang:"dns-server"`
+	NTP        *testNTP   `yang:"ntp"`
+	Users      []testUser `yang:"user"`
+	Ignored    string
+}
+
+type testNTP struct {
+	Server string `yang:"server,omitempty"`
+}
+
+type testUser struct {
+	Name  string  `yang:"name,key"`
+	UID   *uint32 `yang:"uid"`
+	Admin bool    `yang:"admin,empty"`
+}
+
+type testInterfaces struct {
+	Interfaces map[string]testInterface `yang:"interface"`
+}
+
+type testInterface struct {
+	Name    string `yang:"name,key"`
+	MTU     uint16 `yang:"mtu,omitempty"`
+	Enabled bool   `yang:"enabled"`
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// formatEdits describes edits as "path[=value]".
+func formatEdits(edits []sysrepo.Edit) []string {
+	var result []string
+	for _, edit := range edits {
+		formatted := edit.Path
+		if edit.Value != nil {
+			formatted += "=" + *edit.Value
+		}
+		result = append(result, formatted)
+	}
+	return result
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []string
+	}{
+		{
+			name:  "empty",
+			value: testSystem{},
+		},
+		{
+			name: "leaves and presence container",
+			value: &testSystem{
+				Hostname:   ptr("r1"),
+				DNSServers: []string{"b", "a"},
+				NTP:        &testNTP{},
+			},
+			want: []string{"/hostname=r1", "/dns-server=b", "/dns-server=a", "/ntp"},
+		},
+		{
+			name: "list entries with quoted keys",
+			value: testSystem{Users: []testUser{
+				{Name: "it's", UID: ptr(uint32(1000)), Admin: true},
+				{Name: "bob"},
+			}},
+			want: []string{
+				`/user[name="it's"]`,
+				`/user[name="it's"]/uid=1000`,
+				`/user[name="it's"]/admin`,
+				"/user[name='bob']",
+			},
+		},
+		{
+			name: "map keyed by the list key",
+			value: testInterfaces{Interfaces: map[string]testInterface{
+				"eth1": {Name: "eth1", Enabled: true},
+				"eth0": {MTU: 1500},
+			}},
+			want: []string{
+				"/interface[name='eth0']",
+				"/interface[name='eth0']/mtu=1500",
+				"/interface[name='eth0']/enabled=false",
+				"/interface[name='eth1']",
+				"/interface[name='eth1']/enabled=true",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edits, err := sysrepo.Marshal(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := formatEdits(edits)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Marshal() edits:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := sysrepo.Marshal("not a struct")
+	if err == nil {
+		t.Error("Marshal() of a string succeeded")
+	}
+
+	_, err = sysrepo.Marshal(testSystem{Users: []testUser{{Name: `it's "x"`}}})
+	var srErr sysrepo.Error
+	if !errors.As(err, &srErr) || srErr.Code != sysrepo.ErrInvalArg {
+		t.Errorf("Marshal() of a key with both quotes = %v, want ErrInvalArg", err)
+	}
+
+	err = sysrepo.Unmarshal(libyang.DataNode{}, testSystem{})
+	if err == nil {
+		t.Error("Unmarshal() into a non-pointer succeeded")
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	conn := sysrepotest.New(t, "testdata/sysrepo-test.yang")
+	session := sysrepotest.Session(t, conn, sysrepo.DSRunning)
+
+	system := testSystem{
+		Hostname:   ptr("r1"),
+		DNSServers: []string{"10.0.0.2", "10.0.0.1"},
+		NTP:        &testNTP{Server: "pool.ntp.org"},
+		Users: []testUser{
+			{Name: "it's", UID: ptr(uint32(1000)), Admin: true},
+			{Name: "bob"},
+		},
+	}
+	interfaces := testInterfaces{Interfaces: map[string]testInterface{
+		"eth0": {Name: "eth0", MTU: 1500, Enabled: true},
+		"eth1": {Name: "eth1", Enabled: false},
+	}}
+
+	err := session.SetFrom("/sysrepo-test:system", &system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.SetFrom("/sysrepo-test:interfaces", interfaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.ApplyChanges(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotSystem testSystem
+	err = session.GetInto("/sysrepo-test:system", &gotSystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotSystem, system) {
+		t.Errorf("GetInto() = %+v, want %+v", gotSystem, system)
+	}
+
+	var gotInterfaces testInterfaces
+	err = session.GetInto("/sysrepo-test:interfaces", &gotInterfaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotInterfaces, interfaces) {
+		t.Errorf("GetInto() = %+v, want %+v", gotInterfaces, interfaces)
+	}
+}
+
+func TestGetIntoMissing(t *testing.T) {
+	conn := sysrepotest.New(t, "testdata/sysrepo-test.yang")
+	session := sysrepotest.Session(t, conn, sysrepo.DSRunning)
+
+	var system testSystem
+	err := session.GetInto("/sysrepo-test:system", &system)
+	var srErr sysrepo.Error
+	if !errors.As(err, &srErr) || srErr.Code != sysrepo.ErrNotFound {
+		t.Errorf("GetInto() of missing data = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,85 @@
+package sysrepo
+
+/*
+ #cgo LDFLAGS: -lsysrepo
+ #include <stdlib.h>
+ #include <sysrepo.h>
+
+ extern int goModuleChangeCallback(sr_session_ctx_t *session, uint32_t sub_id, char *module_name, char *xpath, sr_event_t event, uint32_t request_id, void *private_data);
+*/
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// ModuleChangeCallback is called for the events of a module change
+// subscription. The session is the implicit event session, valid only during
+// the call; the changes are read with its GetChanges. Returning an error in
+// EvChange or EvUpdate rejects the changes, an Error keeps its code.
+type ModuleChangeCallback func(session *Session, subID uint32, moduleName string, xpath *string, event Event, requestID uint32) error
+
+type moduleChangeSubscriber struct {
+	session  *Session
+	callback ModuleChangeCallback
+}
+
+// ModuleChangeSubscribe subscribes to the changes of moduleName in the active
+// datastore, optionally limited to xpath. Subscriptions with a higher priority
+// are called first.
+func (s *Session) ModuleChangeSubscribe(moduleName string, xpath *string, callback ModuleChangeCallback, priority uint32, opts SubscribeOptions) (*Subscription, error) {
+	moduleNameC, freeModule := stringToC(moduleName)
+	defer freeModule()
+
+	var xpathC *C.char
+	var free func()
+	if xpath != nil {
+		xpathC, free = stringToC(*xpath)
+		defer free()
+	}
+
+	data, freeData := newCallbackData(&moduleChangeSubscriber{
+		session:  s,
+		callback: callback,
+	})
+
+	var sub *C.sr_subscription_ctx_t
+	rc := C.sr_module_change_subscribe(s.sess, moduleNameC, xpathC,
+		(C.sr_module_change_cb)(unsafe.Pointer(C.goModuleChangeCallback)), data, C.uint32_t(priority), C.sr_subscr_options_t(opts), &sub)
+	if rc != C.SR_ERR_OK {
+		freeData()
+		return nil, Error{
+			Message: "Couldn't subscribe to changes of '" + moduleName + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	subscription := newSubscription(s, sub)
+	subscription.cleanupTasks = append(subscription.cleanupTasks, freeData)
+	return subscription, nil
+}
+
+//export goModuleChangeCallback
+func goModuleChangeCallback(session *C.sr_session_ctx_t, subID C.uint32_t, moduleName *C.char, xpath *C.char, event C.sr_event_t, requestID C.uint32_t, privateData unsafe.Pointer) C.int {
+	subscriber := callbackData(privateData).(*moduleChangeSubscriber)
+	eventSess := eventSession(session, subscriber.session)
+
+	var goXpath *string
+	if xpath != nil {
+		s := C.GoString(xpath)
+		goXpath = &s
+	}
+
+	err := subscriber.callback(eventSess, uint32(subID), C.GoString(moduleName), goXpath, Event(event), uint32(requestID))
+	if err == nil {
+		return C.SR_ERR_OK
+	}
+
+	eventSess.SetErrorMessage(err.Error())
+
+	var srErr Error
+	if errors.As(err, &srErr) && srErr.Code != ErrOk {
+		return C.int(srErr.Code)
+	}
+	return C.SR_ERR_CALLBACK_FAILED
+}
@@ -0,0 +1,118 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+// #include <sysrepo/netconf_acm.h>
+import "C"
+import (
+	"strings"
+	"unsafe"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+)
+
+// NacmInit subscribes to the ietf-netconf-acm configuration so that NACM rules
+// are enforced for sessions with a NACM user set. It should be called once per
+// process and the subscription kept for as long as NACM is needed; sessions of
+// the process are not checked before it is called or after the subscription is
+// closed.
+func (s *Session) NacmInit(opts SubscribeOptions) (*Subscription, error) {
+	var sub *C.sr_subscription_ctx_t
+	rc := C.sr_nacm_init(s.sess, C.sr_subscr_options_t(opts), &sub)
+	if rc != C.SR_ERR_OK {
+		return nil, Error{
+			Message: "Couldn't initialize NACM",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	subscription := newSubscription(s, sub)
+	subscription.cleanupTasks = append(subscription.cleanupTasks, func() {
+		C.sr_nacm_destroy()
+	})
+	return subscription, nil
+}
+
+// NacmStats holds the global NACM counters of ietf-netconf-acm.
+type NacmStats struct {
+	DeniedOperations    uint32
+	DeniedDataWrites    uint32
+	DeniedNotifications uint32
+}
+
+func (s *Session) NacmGlobalStats() NacmStats {
+	var operations, dataWrites, notifications C.uint32_t
+	C.sr_nacm_glob_stats(s.sess, &operations, &dataWrites, &notifications)
+
+	return NacmStats{
+		DeniedOperations:    uint32(operations),
+		DeniedDataWrites:    uint32(dataWrites),
+		DeniedNotifications: uint32(notifications),
+	}
+}
+
+// NacmDeniedError is returned when NACM denies access for the NACM user of
+// the session.
+type NacmDeniedError struct {
+	Message string
+	// RuleName is the rule which denied the access, or empty if it was denied
+	// by a default policy.
+	RuleName string
+}
+
+func (e NacmDeniedError) Error() string {
+	return e.Message
+}
+
+func (e NacmDeniedError) Unwrap() error {
+	return Error{
+		Message: e.Message,
+		Code:    ErrUnauthorized,
+	}
+}
+
+// NacmCheckOperation checks whether the NACM user of the session may execute
+// the RPC, action or notification in operation. A NacmDeniedError is returned
+// if not.
+func (s *Session) NacmCheckOperation(operation libyang.DataNode) error {
+	rc := C.sr_nacm_check_operation(s.sess, nodeToC(operation))
+	if rc == C.SR_ERR_UNAUTHORIZED {
+		return s.nacmDenied()
+	}
+	return throwIfError(rc, "Couldn't check NACM access of the operation")
+}
+
+// NacmFilterRead removes all the nodes of tree and its siblings which the NACM
+// user of the session is not allowed to read. The returned tree replaces the
+// passed one and is empty if nothing readable remains.
+func (s *Session) NacmFilterRead(tree libyang.DataNode) (libyang.DataNode, error) {
+	first := nodeToC(tree)
+	rc := C.sr_nacm_check_data_read_filter(s.sess, &first)
+	if rc != C.SR_ERR_OK {
+		return tree, Error{
+			Message: "Couldn't filter data by NACM",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	return libyang.NewNode(unsafe.Pointer(first)), nil
+}
+
+func (s *Session) nacmDenied() error {
+	denied := NacmDeniedError{Message: "Access denied by NACM"}
+
+	errs := s.GetErrors()
+	if len(errs) > 0 {
+		denied.Message = errs[0].Message
+		// sysrepo names the rule as in: ... because "rule" NACM rule is set.
+		if strings.Contains(denied.Message, "NACM rule") {
+			parts := strings.SplitN(denied.Message, "\"", 3)
+			if len(parts) == 3 {
+				denied.RuleName = parts[1]
+			}
+		}
+	}
+
+	return denied
+}
@@ -0,0 +1,208 @@
+package netconf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// element is a parsed XML element. Names are resolved to their namespaces,
+// while the prefix declarations are kept for values that use them, such as
+// identities and XPath filters.
+type element struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	prefixes map[string]string
+	text     string
+	children []*element
+	parent   *element
+}
+
+func parseElement(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, current *element
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			e := &element{name: token.Name, parent: current, prefixes: map[string]string{}}
+			for _, attr := range token.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					e.prefixes[attr.Name.Local] = attr.Value
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				default:
+					e.attrs = append(e.attrs, attr)
+				}
+			}
+
+			switch {
+			case current != nil:
+				current.children = append(current.children, e)
+			case root != nil:
+				return nil, errors.New("more than one root element")
+			default:
+				root = e
+			}
+			current = e
+		case xml.EndElement:
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.text += string(token)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, errors.New("no root element")
+	}
+	return root, nil
+}
+
+// child returns the first child element with the name, nil if there is none.
+func (e *element) child(space string, local string) *element {
+	for _, child := range e.children {
+		if child.name.Space == space && child.name.Local == local {
+			return child
+		}
+	}
+	return nil
+}
+
+func (e *element) attr(local string) (string, bool) {
+	for _, attr := range e.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+func (e *element) value() string {
+	return strings.TrimSpace(e.text)
+}
+
+// scope returns the prefix declarations in scope of the element.
+func (e *element) scope() map[string]string {
+	result := map[string]string{}
+	if e.parent != nil {
+		result = e.parent.scope()
+	}
+	for prefix, namespace := range e.prefixes {
+		result[prefix] = namespace
+	}
+	return result
+}
+
+// content returns the children serialized as standalone XML documents, each
+// declaring the namespaces it needs.
+func (e *element) content() string {
+	var b strings.Builder
+	for _, child := range e.children {
+		child.write(&b, "", true)
+	}
+	return b.String()
+}
+
+// write serializes the element. All prefix declarations in scope are repeated
+// on a root element so identity values keep their meaning.
+func (e *element) write(b *strings.Builder, parentSpace string, root bool) {
+	b.WriteString("<" + e.name.Local)
+	if e.name.Space != parentSpace {
+		writeAttr(b, "xmlns", e.name.Space)
+	}
+
+	if root {
+		scope := e.scope()
+		prefixes := make([]string, 0, len(scope))
+		for prefix := range scope {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			writeAttr(b, "xmlns:"+prefix, scope[prefix])
+		}
+	} else {
+		for prefix, namespace := range e.prefixes {
+			writeAttr(b, "xmlns:"+prefix, namespace)
+		}
+	}
+
+	scope := e.scope()
+	for _, attr := range e.attrs {
+		switch attr.Name.Space {
+		case "":
+			writeAttr(b, attr.Name.Local, attr.Value)
+		case xmlNamespace:
+			writeAttr(b, "xml:"+attr.Name.Local, attr.Value)
+		default:
+			prefix := declaredPrefix(scope, attr.Name.Space)
+			if prefix == "" {
+				// Declare a prefix unused in scope on this element.
+				for i := len(scope); prefix == ""; i++ {
+					candidate := "ns" + strconv.Itoa(i)
+					if _, used := scope[candidate]; !used {
+						prefix = candidate
+					}
+				}
+				scope[prefix] = attr.Name.Space
+				writeAttr(b, "xmlns:"+prefix, attr.Name.Space)
+			}
+			writeAttr(b, prefix+":"+attr.Name.Local, attr.Value)
+		}
+	}
+
+	if len(e.children) == 0 {
+		b.WriteString(">")
+		xml.EscapeText(b, []byte(e.text))
+	} else {
+		b.WriteString(">")
+		for _, child := range e.children {
+			child.write(b, e.name.Space, false)
+		}
+	}
+	b.WriteString("</" + e.name.Local + ">")
+}
+
+// declaredPrefix returns a prefix of namespace in scope, empty if there is
+// none.
+func declaredPrefix(scope map[string]string, namespace string) string {
+	prefixes := make([]string, 0, len(scope))
+	for prefix := range scope {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if scope[prefix] == namespace {
+			return prefix
+		}
+	}
+	return ""
+}
+
+func writeAttr(b *strings.Builder, name string, value string) {
+	b.WriteString(" " + name + "=\"")
+	xml.EscapeText(b, []byte(value))
+	b.WriteString("\"")
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
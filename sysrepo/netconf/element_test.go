@@ -0,0 +1,155 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestParseElement(t *testing.T) {
+	e, err := parseElement([]byte(`<?xml version="1.0"?>
+<rpc xmlns="urn:base" xmlns:t="urn:t" message-id="101">
+  <t:get><t:name> eth0 </t:name></t:get>
+</rpc>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.name != (xml.Name{Space: "urn:base", Local: "rpc"}) {
+		t.Errorf("name %v", e.name)
+	}
+	if id, ok := e.attr("message-id"); !ok || id != "101" {
+		t.Errorf("message-id %q, %t", id, ok)
+	}
+	if len(e.attrs) != 1 {
+		t.Errorf("attributes %v, want only message-id", e.attrs)
+	}
+
+	name := e.child("urn:t", "get").child("urn:t", "name")
+	if name == nil || name.value() != "eth0" {
+		t.Fatalf("name %+v", name)
+	}
+	if scope := name.scope(); len(scope) != 1 || scope["t"] != "urn:t" {
+		t.Errorf("scope %v", scope)
+	}
+	if e.child("urn:base", "get") != nil {
+		t.Error("found get in the wrong namespace")
+	}
+}
+
+func TestParseElementErrors(t *testing.T) {
+	tests := map[string]string{
+		"":               "no root element",
+		"  \n":           "no root element",
+		"<a/><b/>":       "more than one root element",
+		"<a>":            "XML syntax error on line 1: unexpected EOF",
+		"<a></b>":        "XML syntax error on line 1: element <a> closed by </b>",
+		"<a><![CDATA[x]": "XML syntax error on line 1: unexpected EOF in CDATA section",
+	}
+	for input, want := range tests {
+		_, err := parseElement([]byte(input))
+		if err == nil || err.Error() != want {
+			t.Errorf("parseElement(%q): %v, want %s", input, err, want)
+		}
+	}
+}
+
+func TestContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "default namespace",
+			input: `<config xmlns="urn:nc"><top xmlns="urn:a"><leaf>v</leaf></top></config>`,
+			want:  `<top xmlns="urn:a"><leaf>v</leaf></top>`,
+		},
+		{
+			name:  "several roots",
+			input: `<config xmlns="urn:nc"><a xmlns="urn:a"/><b xmlns="urn:b"/></config>`,
+			want:  `<a xmlns="urn:a"></a><b xmlns="urn:b"></b>`,
+		},
+		{
+			name:  "prefixes of ancestors",
+			input: `<rpc xmlns="urn:nc" xmlns:z="urn:z"><config xmlns:b="urn:b"><top xmlns="urn:a"><type>b:eth</type></top></config></rpc>`,
+			want:  `<top xmlns="urn:a" xmlns:b="urn:b" xmlns:z="urn:z"><type>b:eth</type></top>`,
+		},
+		{
+			name:  "prefix of a descendant",
+			input: `<config xmlns="urn:nc"><top xmlns="urn:a"><type xmlns:b="urn:b">b:eth</type></top></config>`,
+			want:  `<top xmlns="urn:a"><type xmlns:b="urn:b">b:eth</type></top>`,
+		},
+		{
+			name:  "prefixed element",
+			input: `<config xmlns="urn:nc" xmlns:a="urn:a"><a:top><a:leaf>v</a:leaf></a:top></config>`,
+			want:  `<top xmlns="urn:a" xmlns:a="urn:a"><leaf>v</leaf></top>`,
+		},
+		{
+			name:  "attribute of a declared namespace",
+			input: `<config xmlns="urn:nc" xmlns:nc="urn:nc"><top xmlns="urn:a"><leaf nc:operation="delete"/></top></config>`,
+			want:  `<top xmlns="urn:a" xmlns:nc="urn:nc"><leaf nc:operation="delete"></leaf></top>`,
+		},
+		{
+			name:  "xml attribute",
+			input: `<config xmlns="urn:nc"><top xmlns="urn:a" xml:lang="en"/></config>`,
+			want:  `<top xmlns="urn:a" xml:lang="en"></top>`,
+		},
+		{
+			name:  "escaping",
+			input: `<config xmlns="urn:nc"><top xmlns="urn:a" note="&quot;a&amp;b&quot;">1 &lt; 2</top></config>`,
+			want:  `<top xmlns="urn:a" note="&#34;a&amp;b&#34;">1 &lt; 2</top>`,
+		},
+		{
+			name:  "empty",
+			input: `<config xmlns="urn:nc"/>`,
+			want:  ``,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e, err := parseElement([]byte(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Take the config of an rpc, as edit-config does.
+			if config := e.child("urn:nc", "config"); config != nil {
+				e = config
+			}
+			if got := e.content(); got != test.want {
+				t.Errorf("content:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteUndeclaredAttributeNamespace(t *testing.T) {
+	config := &element{
+		name:     xml.Name{Space: "urn:nc", Local: "config"},
+		prefixes: map[string]string{"ns1": "urn:one"},
+	}
+	top := &element{
+		name:     xml.Name{Space: "urn:a", Local: "top"},
+		prefixes: map[string]string{},
+		parent:   config,
+		attrs: []xml.Attr{
+			{Name: xml.Name{Space: "urn:two", Local: "x"}, Value: "1"},
+			{Name: xml.Name{Space: "urn:two", Local: "y"}, Value: "2"},
+			{Name: xml.Name{Space: "urn:one", Local: "z"}, Value: "3"},
+		},
+	}
+	config.children = []*element{top}
+
+	// ns1 is in use, so the first free prefix of urn:two is ns2, which is
+	// then reused for the other attribute of urn:two.
+	want := `<top xmlns="urn:a" xmlns:ns1="urn:one" xmlns:ns2="urn:two" ns2:x="1" ns2:y="2" ns1:z="3"></top>`
+	if got := config.content(); got != want {
+		t.Errorf("content:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The declarations of the attributes don't leak into the scope of the
+	// element.
+	if scope := top.scope(); len(scope) != 1 {
+		t.Errorf("scope %v", scope)
+	}
+}
@@ -0,0 +1,94 @@
+package netconf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+// rpcError is an <rpc-error> of a reply (RFC 6241, section 4.3).
+type rpcError struct {
+	Type    string
+	Tag     string
+	Message string
+	Info    string // Content of error-info, in XML
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func (e *rpcError) xml() string {
+	var b strings.Builder
+	b.WriteString("<rpc-error><error-type>" + e.Type + "</error-type><error-tag>" + e.Tag + "</error-tag><error-severity>error</error-severity>")
+	if e.Message != "" {
+		b.WriteString(`<error-message xml:lang="en">` + escape(e.Message) + "</error-message>")
+	}
+	if e.Info != "" {
+		b.WriteString("<error-info>" + e.Info + "</error-info>")
+	}
+	b.WriteString("</rpc-error>")
+	return b.String()
+}
+
+func notSupported(operation string) *rpcError {
+	return &rpcError{Type: "protocol", Tag: "operation-not-supported", Message: "'" + operation + "' is not supported"}
+}
+
+// lockDenied returns the error of a lock held by the NETCONF session holder,
+// which is 0 if the lock is not held by a NETCONF session.
+func lockDenied(holder uint32) *rpcError {
+	id := strconv.FormatUint(uint64(holder), 10)
+	message := "The datastore is locked by session " + id
+	if holder == 0 {
+		message = "The datastore is locked outside of NETCONF"
+	}
+	return &rpcError{Type: "protocol", Tag: "lock-denied", Message: message, Info: "<session-id>" + id + "</session-id>"}
+}
+
+func missingElement(name string) *rpcError {
+	return &rpcError{Type: "protocol", Tag: "missing-element", Message: "The '" + name + "' parameter is missing"}
+}
+
+// replyError converts err into an rpc-error, with the messages the sysrepo
+// session holds about it.
+func (s *session) replyError(err error) *rpcError {
+	var netconfErr *rpcError
+	if errors.As(err, &netconfErr) {
+		return netconfErr
+	}
+
+	result := &rpcError{Type: "application", Tag: "operation-failed", Message: err.Error()}
+	var messages []string
+	for _, info := range s.sr.GetErrors() {
+		if info.Message != "" {
+			messages = append(messages, info.Message)
+		}
+	}
+	if len(messages) > 0 {
+		result.Message = strings.Join(messages, "; ")
+	}
+
+	var srErr sysrepo.Error
+	if !errors.As(err, &srErr) {
+		return result
+	}
+
+	switch srErr.Code {
+	case sysrepo.ErrNotFound:
+		result.Tag = "data-missing"
+	case sysrepo.ErrExists:
+		result.Tag = "data-exists"
+	case sysrepo.ErrUnauthorized:
+		result.Tag = "access-denied"
+	case sysrepo.ErrLocked:
+		result.Type, result.Tag = "protocol", "in-use"
+	case sysrepo.ErrInvalArg, sysrepo.ErrLibyang, sysrepo.ErrValidationFailed:
+		result.Tag = "invalid-value"
+	case sysrepo.ErrUnsupported:
+		result.Tag = "operation-not-supported"
+	}
+	return result
+}
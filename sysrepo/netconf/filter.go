@@ -0,0 +1,144 @@
+package netconf
+
+import (
+	"strings"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo/xpath"
+)
+
+// filterXpath returns the XPath selecting the data of a NETCONF filter
+// element, all data if filter is nil.
+func (s *session) filterXpath(filter *element) (string, error) {
+	if filter == nil {
+		return "/*", nil
+	}
+
+	filterType, _ := filter.attr("type")
+	switch filterType {
+	case "xpath":
+		selection, ok := filter.attr("select")
+		if !ok {
+			return "", &rpcError{Type: "protocol", Tag: "missing-attribute", Message: "The XPath filter has no select attribute"}
+		}
+		return s.xpathModules(selection, filter.scope())
+	case "", "subtree":
+		return s.subtreeXpath(filter)
+	}
+	return "", &rpcError{Type: "protocol", Tag: "bad-attribute", Message: "Unknown filter type '" + filterType + "'"}
+}
+
+// xpathModules replaces the XML prefixes of an XPath expression by the names
+// of the modules of their namespaces, as expected by sysrepo.
+func (s *session) xpathModules(expr string, scope map[string]string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if c == '\'' || c == '"' {
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				b.WriteString(expr[i:])
+				break
+			}
+			b.WriteString(expr[i : i+end+2])
+			i += end + 1
+			continue
+		}
+
+		if !isNameStart(c) || (i > 0 && isNameChar(expr[i-1])) {
+			b.WriteByte(c)
+			continue
+		}
+
+		end := i + 1
+		for end < len(expr) && isNameChar(expr[end]) {
+			end++
+		}
+		name := expr[i:end]
+		namespace, declared := scope[name]
+		if end < len(expr) && expr[end] == ':' && (end+1 >= len(expr) || expr[end+1] != ':') && declared {
+			module, err := s.module(namespace)
+			if err != nil {
+				return "", err
+			}
+			name = module
+		}
+		b.WriteString(name)
+		i = end - 1
+	}
+	return b.String(), nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c == '-' || c == '.' || (c >= '0' && c <= '9')
+}
+
+// subtreeXpath converts a subtree filter (RFC 6241, section 6) into the union
+// of the XPaths of its selections. Attribute matches are not supported.
+func (s *session) subtreeXpath(filter *element) (string, error) {
+	var paths []string
+	for _, child := range filter.children {
+		childPaths, err := s.subtreePaths(child, nil)
+		if err != nil {
+			return "", err
+		}
+		paths = append(paths, childPaths...)
+	}
+
+	if len(paths) == 0 {
+		return "", &rpcError{Type: "application", Tag: "invalid-value", Message: "Empty subtree filter"}
+	}
+	return strings.Join(paths, " | "), nil
+}
+
+// subtreePaths returns the paths selected by a filter node below parent.
+// Content match nodes become predicates, and containment nodes select the
+// paths of their children along with the matched content.
+func (s *session) subtreePaths(node *element, parent xpath.Path) ([]string, error) {
+	module, err := s.module(node.name.Space)
+	if err != nil {
+		return nil, err
+	}
+
+	path := parent.Child(module + ":" + node.name.Local)
+	var matches []string
+	var containment []*element
+	for _, child := range node.children {
+		if len(child.children) > 0 || child.value() == "" {
+			containment = append(containment, child)
+			continue
+		}
+
+		childModule, err := s.module(child.name.Space)
+		if err != nil {
+			return nil, err
+		}
+
+		name := child.name.Local
+		if childModule != module {
+			name = childModule + ":" + name
+		}
+		path = path.Key(name, child.value())
+		matches = append(matches, name)
+	}
+
+	if len(containment) == 0 {
+		return []string{path.String()}, nil
+	}
+
+	var result []string
+	for _, match := range matches {
+		result = append(result, path.Child(match).String())
+	}
+	for _, child := range containment {
+		childPaths, err := s.subtreePaths(child, path)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, childPaths...)
+	}
+	return result, nil
+}
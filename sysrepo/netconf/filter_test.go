@@ -0,0 +1,158 @@
+package netconf
+
+import (
+	"errors"
+	"testing"
+)
+
+// testSession returns a session knowing the modules of the namespaces urn:a
+// and urn:b, which needs no connection as long as no other namespace is used.
+func testSession() *session {
+	return &session{modules: map[string]string{"urn:a": "a", "urn:b": "b"}}
+}
+
+func expectRPCError(t *testing.T, err error, tag string) {
+	t.Helper()
+
+	var netconfErr *rpcError
+	if !errors.As(err, &netconfErr) || netconfErr.Tag != tag {
+		t.Errorf("error %v, want %s", err, tag)
+	}
+}
+
+func TestXpathModules(t *testing.T) {
+	scope := map[string]string{"x": "urn:a", "y": "urn:b", "child": "urn:a"}
+	tests := map[string]string{
+		"/x:top/x:leaf":                      "/a:top/a:leaf",
+		"/x:top/y:augment":                   "/a:top/b:augment",
+		"/x:top[x:name='x:eth0']":            "/a:top[a:name='x:eth0']",
+		`/x:top[x:name="it's x:eth0"]`:       `/a:top[a:name="it's x:eth0"]`,
+		"/x:top[x:type=concat('x:', \"y\")]": "/a:top[a:type=concat('x:', \"y\")]",
+		"/x:top/child::x:leaf":               "/a:top/child::a:leaf",
+		"/x:top/xx:leaf":                     "/a:top/xx:leaf",
+		"/z:top":                             "/z:top",
+		"/x:top[x:mtu > 1500] | /y:other":    "/a:top[a:mtu > 1500] | /b:other",
+		"count(/x:top/x:leaf) = 2":           "count(/a:top/a:leaf) = 2",
+		"/x:top[x:name='x:eth0":              "/a:top[a:name='x:eth0",
+		"/x:":                                "/a:",
+		"/*":                                 "/*",
+	}
+
+	for expr, want := range tests {
+		got, err := testSession().xpathModules(expr, scope)
+		if err != nil || got != want {
+			t.Errorf("xpathModules(%s) = %s, %v, want %s", expr, got, err, want)
+		}
+	}
+}
+
+func TestSubtreeXpath(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{
+			name:   "selection",
+			filter: `<filter><top xmlns="urn:a"/></filter>`,
+			want:   "/a:top",
+		},
+		{
+			name:   "several selections",
+			filter: `<filter><top xmlns="urn:a"/><other xmlns="urn:b"/></filter>`,
+			want:   "/a:top | /b:other",
+		},
+		{
+			name:   "containment",
+			filter: `<filter><top xmlns="urn:a"><user><uid/></user></top></filter>`,
+			want:   "/a:top/user/uid",
+		},
+		{
+			name:   "content match",
+			filter: `<filter><top xmlns="urn:a"><user><name>fred</name></user></top></filter>`,
+			want:   "/a:top/user[name='fred']",
+		},
+		{
+			name:   "content match with selections",
+			filter: `<filter><top xmlns="urn:a"><user><name>fred</name><uid/><admin/></user></top></filter>`,
+			want:   "/a:top/user[name='fred']/name | /a:top/user[name='fred']/uid | /a:top/user[name='fred']/admin",
+		},
+		{
+			name:   "several content matches",
+			filter: `<filter><top xmlns="urn:a"><route><prefix>10.0.0.0/8</prefix><table> 2 </table></route></top></filter>`,
+			want:   "/a:top/route[prefix='10.0.0.0/8'][table='2']",
+		},
+		{
+			name:   "content match of another module",
+			filter: `<filter><top xmlns="urn:a"><user><role xmlns="urn:b">admin</role></user></top></filter>`,
+			want:   "/a:top/user[b:role='admin']",
+		},
+		{
+			name:   "child of another module",
+			filter: `<filter><top xmlns="urn:a"><user><name>fred</name><role xmlns="urn:b"/></user></top></filter>`,
+			want:   "/a:top/user[name='fred']/name | /a:top/user[name='fred']/b:role",
+		},
+		{
+			name:   "quote in a value",
+			filter: `<filter><top xmlns="urn:a"><user><name>o'brien</name></user></top></filter>`,
+			want:   `/a:top/user[name="o'brien"]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := parseElement([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := testSession().subtreeXpath(filter)
+			if err != nil || got != test.want {
+				t.Errorf("subtreeXpath = %s, %v, want %s", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestSubtreeXpathEmpty(t *testing.T) {
+	filter, err := parseElement([]byte(`<filter> </filter>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = testSession().subtreeXpath(filter)
+	expectRPCError(t, err, "invalid-value")
+}
+
+func TestFilterXpath(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+		tag    string
+	}{
+		{filter: "", want: "/*"},
+		{filter: `<filter xmlns:x="urn:a" type="xpath" select="/x:top"/>`, want: "/a:top"},
+		{filter: `<filter type="subtree"><top xmlns="urn:b"/></filter>`, want: "/b:top"},
+		{filter: `<filter><top xmlns="urn:b"/></filter>`, want: "/b:top"},
+		{filter: `<filter type="xpath"/>`, tag: "missing-attribute"},
+		{filter: `<filter type="regexp" select="/x:top"/>`, tag: "bad-attribute"},
+	}
+
+	for _, test := range tests {
+		var filter *element
+		if test.filter != "" {
+			var err error
+			filter, err = parseElement([]byte(test.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := testSession().filterXpath(filter)
+		if test.tag != "" {
+			expectRPCError(t, err, test.tag)
+		} else if err != nil || got != test.want {
+			t.Errorf("filterXpath(%s) = %s, %v, want %s", test.filter, got, err, test.want)
+		}
+	}
+}
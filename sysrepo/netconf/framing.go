@@ -0,0 +1,123 @@
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxMessageSize limits the size of a received message.
+const maxMessageSize = 64 << 20
+
+var endOfMessage = []byte("]]>]]>")
+
+// transport reads and writes messages with the end-of-message framing of
+// NETCONF 1.0 or, once both peers announced base:1.1, the chunked framing of
+// RFC 6242.
+type transport struct {
+	r       *bufio.Reader
+	w       *bufio.Writer
+	chunked bool
+}
+
+func newTransport(rw io.ReadWriter) *transport {
+	return &transport{
+		r: bufio.NewReader(rw),
+		w: bufio.NewWriter(rw),
+	}
+}
+
+// readMessage returns the next message, or io.EOF if the peer closed the
+// connection between messages.
+func (t *transport) readMessage() ([]byte, error) {
+	if t.chunked {
+		return t.readChunked()
+	}
+	return t.readEndOfMessage()
+}
+
+func (t *transport) readEndOfMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		part, err := t.r.ReadSlice('>')
+		msg = append(msg, part...)
+		if bytes.HasSuffix(msg, endOfMessage) {
+			return msg[:len(msg)-len(endOfMessage)], nil
+		}
+
+		switch {
+		case len(msg) > maxMessageSize:
+			return nil, errors.New("message too large")
+		case errors.Is(err, io.EOF) && len(bytes.TrimSpace(msg)) == 0:
+			return nil, io.EOF
+		case errors.Is(err, io.EOF):
+			return nil, io.ErrUnexpectedEOF
+		case err != nil && !errors.Is(err, bufio.ErrBufferFull):
+			return nil, err
+		}
+	}
+}
+
+func (t *transport) readChunked() ([]byte, error) {
+	var msg []byte
+	for {
+		header, err := t.r.ReadString('\n')
+		if errors.Is(err, io.EOF) && msg == nil && header == "" {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if header != "\n" {
+			return nil, fmt.Errorf("invalid chunk start %q", header)
+		}
+
+		header, err = t.r.ReadString('\n')
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if header == "##\n" {
+			return msg, nil
+		}
+
+		digits, ok := strings.CutPrefix(strings.TrimSuffix(header, "\n"), "#")
+		size, err := strconv.ParseUint(digits, 10, 32)
+		if !ok || err != nil || size == 0 {
+			return nil, fmt.Errorf("invalid chunk header %q", header)
+		}
+		if len(msg)+int(size) > maxMessageSize {
+			return nil, errors.New("message too large")
+		}
+
+		chunk := make([]byte, size)
+		_, err = io.ReadFull(t.r, chunk)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		msg = append(msg, chunk...)
+	}
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF for an io.EOF within a message.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (t *transport) writeMessage(msg []byte) error {
+	if t.chunked {
+		fmt.Fprintf(t.w, "\n#%d\n", len(msg))
+		t.w.Write(msg)
+		t.w.WriteString("\n##\n")
+	} else {
+		t.w.Write(msg)
+		t.w.Write(endOfMessage)
+	}
+	return t.w.Flush()
+}
@@ -0,0 +1,127 @@
+package netconf
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readAll reads the messages of input with the smallest buffer bufio allows,
+// one byte at a time, so delimiters and chunks straddle buffer boundaries.
+// It returns the messages and the error ending them.
+func readAll(input string, chunked bool) ([]string, error) {
+	t := &transport{
+		r:       bufio.NewReaderSize(iotest.OneByteReader(strings.NewReader(input)), 16),
+		chunked: chunked,
+	}
+
+	var messages []string
+	for {
+		msg, err := t.readMessage()
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, string(msg))
+	}
+}
+
+func TestReadEndOfMessage(t *testing.T) {
+	long := strings.Repeat("x", 40)
+	tests := []struct {
+		name     string
+		input    string
+		messages []string
+		err      string
+	}{
+		{"single", "<hello/>]]>]]>", []string{"<hello/>"}, "EOF"},
+		{"several", "<a/>]]>]]><b/>]]>]]>", []string{"<a/>", "<b/>"}, "EOF"},
+		{"trailing whitespace", "<a/>]]>]]>\n  ", []string{"<a/>"}, "EOF"},
+		{"empty", "", nil, "EOF"},
+		{"longer than the buffer", "<a>" + long + "</a>]]>]]>", []string{"<a>" + long + "</a>"}, "EOF"},
+		{"delimiter across the buffer end", long[:14] + "]]>]]>", []string{long[:14]}, "EOF"},
+		{"many '>'", "<a><b>]]></b>]]</a>]]>]]>", []string{"<a><b>]]></b>]]</a>"}, "EOF"},
+		{"unterminated", "<a/>]]>]]><b/>", []string{"<a/>"}, "unexpected EOF"},
+		{"partial delimiter", "<a/>]]>]]", nil, "unexpected EOF"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages, err := readAll(test.input, false)
+			if strings.Join(messages, ",") != strings.Join(test.messages, ",") {
+				t.Errorf("messages %q, want %q", messages, test.messages)
+			}
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestReadChunked(t *testing.T) {
+	long := strings.Repeat("x", 40)
+	tests := []struct {
+		name     string
+		input    string
+		messages []string
+		err      string
+	}{
+		{"single chunk", "\n#4\n<a/>\n##\n", []string{"<a/>"}, "EOF"},
+		{"several chunks", "\n#3\n<a>\n#4\n</a>\n##\n", []string{"<a></a>"}, "EOF"},
+		{"several messages", "\n#4\n<a/>\n##\n\n#4\n<b/>\n##\n", []string{"<a/>", "<b/>"}, "EOF"},
+		{"framing in the data", "\n#8\n\n##\n]]>\n\n##\n", []string{"\n##\n]]>\n"}, "EOF"},
+		{"longer than the buffer", "\n#40\n" + long + "\n##\n", []string{long}, "EOF"},
+		{"empty", "", nil, "EOF"},
+		{"missing newline", "#4\n<a/>\n##\n", nil, `invalid chunk start "#4\n"`},
+		{"missing hash", "\n4\n<a/>\n##\n", nil, `invalid chunk header "4\n"`},
+		{"zero size", "\n#0\n\n##\n", nil, `invalid chunk header "#0\n"`},
+		{"invalid size", "\n#abc\n", nil, `invalid chunk header "#abc\n"`},
+		{"size out of range", "\n#4294967296\n", nil, `invalid chunk header "#4294967296\n"`},
+		{"too large", "\n#67108865\n", nil, "message too large"},
+		{"truncated chunk", "\n#4\n<a", nil, "unexpected EOF"},
+		{"missing end", "\n#4\n<a/>", nil, "unexpected EOF"},
+		{"truncated header", "\n#4", nil, "unexpected EOF"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			messages, err := readAll(test.input, true)
+			if strings.Join(messages, ",") != strings.Join(test.messages, ",") {
+				t.Errorf("messages %q, want %q", messages, test.messages)
+			}
+			if err == nil || err.Error() != test.err {
+				t.Errorf("error %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	tests := []struct {
+		chunked bool
+		want    string
+	}{
+		{false, "<a/>]]>]]>"},
+		{true, "\n#4\n<a/>\n##\n"},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		transport := newTransport(&buf)
+		transport.chunked = test.chunked
+
+		err := transport.writeMessage([]byte("<a/>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.want {
+			t.Errorf("chunked %t: wrote %q, want %q", test.chunked, buf.String(), test.want)
+		}
+
+		msg, err := transport.readMessage()
+		if err != nil || string(msg) != "<a/>" {
+			t.Errorf("chunked %t: read %q, %v", test.chunked, msg, err)
+		}
+	}
+}
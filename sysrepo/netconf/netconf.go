@@ -0,0 +1,145 @@
+// Package netconf is a NETCONF (RFC 6241) server on top of sysrepo sessions.
+//
+// It implements the base:1.0 and base:1.1 framing, the get, get-config,
+// edit-config, copy-config, lock, unlock, commit, discard-changes and
+// close-session operations, get-data of NMDA (RFC 8526), and sends any other
+// operation to the sysrepo subscribers of the YANG RPC with its name. Secure
+// transport is left to the host, e.g. by running ServeStdio as an sshd
+// "netconf" subsystem, or by serving a Unix socket whose peers are identified
+// by their credentials.
+//
+// Every NETCONF session uses its own sysrepo session with the NACM user of the
+// client, see Session.SetNacmUser for when sysrepo enforces its rules.
+package netconf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+// Server serves NETCONF sessions on a sysrepo connection.
+type Server struct {
+	conn     *sysrepo.Connection
+	lastID   atomic.Uint32
+	sessions sync.Map // NETCONF session IDs by sysrepo session ID
+	// Timeout of reading data, applying changes and sending RPCs, the
+	// sysrepo default if 0.
+	Timeout time.Duration
+	// Logger receives the errors of the sessions served by ServeUnix, which
+	// are discarded if it is nil.
+	Logger *slog.Logger
+}
+
+// NewServer returns a server using conn.
+func NewServer(conn *sysrepo.Connection) *Server {
+	return &Server{conn: conn}
+}
+
+// Serve runs a NETCONF session for user on rw until the client closes the
+// session or the connection. An empty user disables NACM for the session.
+func (s *Server) Serve(rw io.ReadWriter, user string) error {
+	srSession, err := s.conn.SessionStart(sysrepo.DSRunning)
+	if err != nil {
+		return err
+	}
+
+	if user != "" {
+		err = srSession.SetNacmUser(user)
+		if err != nil {
+			srSession.Close()
+			return err
+		}
+	}
+
+	sess := &session{
+		server:    s,
+		id:        s.lastID.Add(1),
+		transport: newTransport(rw),
+		sr:        srSession,
+		locks:     map[sysrepo.Datastore]*sysrepo.Lock{},
+		modules:   map[string]string{},
+	}
+	s.sessions.Store(srSession.GetId(), sess.id)
+	defer sess.close()
+
+	return sess.run()
+}
+
+// ServeStdio runs a NETCONF session for user on the standard input and
+// output, as an sshd subsystem does. The user of such a subsystem is the one
+// of the process, returned by os/user.Current.
+func (s *Server) ServeStdio(user string) error {
+	return s.Serve(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, user)
+}
+
+// ServeUnix accepts connections on l and runs a NETCONF session on each, for
+// the user owning the client process.
+func (s *Server) ServeUnix(l *net.UnixListener) error {
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			user, err := peerUser(conn)
+			if err == nil {
+				err = s.Serve(conn, user)
+			}
+			if err != nil && s.Logger != nil {
+				s.Logger.Error("NETCONF session failed", "error", err)
+			}
+		}()
+	}
+}
+
+// ListenAndServeUnix listens on the Unix socket at path and calls ServeUnix.
+func (s *Server) ListenAndServeUnix(path string) error {
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	return s.ServeUnix(l)
+}
+
+// peerUser returns the name of the user of the process connected to conn.
+func peerUser(conn *net.UnixConn) (string, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err = errors.Join(err, credErr); err != nil {
+		return "", fmt.Errorf("reading peer credentials: %w", err)
+	}
+
+	u, err := user.LookupId(strconv.Itoa(int(cred.Uid)))
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
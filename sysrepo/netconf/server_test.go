@@ -0,0 +1,268 @@
+package netconf
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepotest"
+)
+
+const (
+	testSchema    = "../testdata/sysrepo-test.yang"
+	testNamespace = "urn:go-sysrepo:test"
+)
+
+// serve runs a session of server on a pipe and returns the client end, and
+// the channel receiving the result of Serve. The pipe has a deadline, so a
+// framing mismatch fails the test instead of hanging it.
+func serve(t *testing.T, server *Server) (*transport, <-chan error) {
+	t.Helper()
+
+	clientSide, serverSide := net.Pipe()
+	clientSide.SetDeadline(time.Now().Add(10 * time.Second))
+
+	result := make(chan error, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		result <- server.Serve(serverSide, "")
+		serverSide.Close()
+	}()
+	t.Cleanup(func() {
+		clientSide.Close()
+		<-finished
+	})
+
+	return newTransport(clientSide), result
+}
+
+type client struct {
+	t         *testing.T
+	transport *transport
+	result    <-chan error
+	hello     *element
+	lastID    int
+}
+
+// connect starts a session announcing the capability for the NETCONF
+// version, base10 or base11.
+func connect(t *testing.T, server *Server, version string) *client {
+	t.Helper()
+
+	c := &client{t: t}
+	c.transport, c.result = serve(t, server)
+
+	msg, err := c.transport.readMessage()
+	if err != nil {
+		t.Fatalf("reading the hello: %v", err)
+	}
+	c.hello, err = parseElement(msg)
+	if err != nil {
+		t.Fatalf("parsing the hello: %v", err)
+	}
+
+	err = c.transport.writeMessage([]byte(`<hello xmlns="` + baseNamespace + `"><capabilities><capability>` + version + `</capability></capabilities></hello>`))
+	if err != nil {
+		t.Fatalf("sending the hello: %v", err)
+	}
+	c.transport.chunked = version == base11
+	return c
+}
+
+// rpc sends an operation and returns the reply. The rpc element declares the
+// base namespace with the prefix nc, for operation attributes.
+func (c *client) rpc(operation string) *element {
+	c.t.Helper()
+
+	c.lastID++
+	id := strconv.Itoa(c.lastID)
+	err := c.transport.writeMessage([]byte(`<rpc xmlns="` + baseNamespace + `" xmlns:nc="` + baseNamespace + `" message-id="` + id + `">` + operation + `</rpc>`))
+	if err != nil {
+		c.t.Fatalf("sending rpc %s: %v", id, err)
+	}
+
+	msg, err := c.transport.readMessage()
+	if err != nil {
+		c.t.Fatalf("reading the reply to rpc %s: %v", id, err)
+	}
+	reply, err := parseElement(msg)
+	if err != nil || reply.name != baseName("rpc-reply") {
+		c.t.Fatalf("invalid reply to rpc %s: %s", id, msg)
+	}
+	if got, _ := reply.attr("message-id"); got != id {
+		c.t.Errorf("reply message-id %s, want %s", got, id)
+	}
+	return reply
+}
+
+// descendant returns the element at the path of local names in space below
+// e, nil if there is none.
+func descendant(e *element, space string, path ...string) *element {
+	for _, local := range path {
+		if e == nil {
+			return nil
+		}
+		e = e.child(space, local)
+	}
+	return e
+}
+
+func expectOK(t *testing.T, reply *element) {
+	t.Helper()
+
+	if reply.child(baseNamespace, "ok") == nil {
+		t.Errorf("reply %s, want ok", reply.content())
+	}
+}
+
+// expectError checks that the reply is an rpc-error with the tag, and
+// returns the error.
+func expectError(t *testing.T, reply *element, tag string) *element {
+	t.Helper()
+
+	rpcErr := reply.child(baseNamespace, "rpc-error")
+	if got := descendant(rpcErr, baseNamespace, "error-tag"); got == nil || got.value() != tag {
+		t.Fatalf("reply %s, want %s", reply.content(), tag)
+	}
+	return rpcErr
+}
+
+func TestServeHello(t *testing.T) {
+	server := NewServer(sysrepotest.New(t))
+	c := connect(t, server, base11)
+
+	if id := descendant(c.hello, baseNamespace, "session-id"); id == nil || id.value() != "1" {
+		t.Errorf("hello %s, want session-id 1", c.hello.content())
+	}
+
+	capabilities := descendant(c.hello, baseNamespace, "capabilities")
+	if capabilities == nil {
+		t.Fatalf("hello without capabilities: %s", c.hello.content())
+	}
+	announced := map[string]bool{}
+	var yangLibrary string
+	for _, capability := range capabilities.children {
+		announced[capability.value()] = true
+		if strings.HasPrefix(capability.value(), "urn:ietf:params:netconf:capability:yang-library:1.1?") {
+			yangLibrary = capability.value()
+		}
+	}
+	for _, capability := range []string{base10, base11, "urn:ietf:params:netconf:capability:rollback-on-error:1.0"} {
+		if !announced[capability] {
+			t.Errorf("%s not announced", capability)
+		}
+	}
+	if !strings.Contains(yangLibrary, "&content-id=") {
+		t.Errorf("yang-library capability %q without content-id", yangLibrary)
+	}
+
+	// The reply is read with the chunked framing.
+	expectOK(t, c.rpc(`<close-session/>`))
+	if err := <-c.result; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+}
+
+func TestServeBase10(t *testing.T) {
+	server := NewServer(sysrepotest.New(t, testSchema))
+	c := connect(t, server, base10)
+
+	reply := c.rpc(`<get-config><source><running/></source></get-config>`)
+	if reply.child(baseNamespace, "data") == nil {
+		t.Errorf("reply %s, want data", reply.content())
+	}
+
+	expectOK(t, c.rpc(`<close-session/>`))
+	if err := <-c.result; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+}
+
+func TestServeNoCommonVersion(t *testing.T) {
+	server := NewServer(sysrepotest.New(t))
+	transport, result := serve(t, server)
+
+	_, err := transport.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = transport.writeMessage([]byte(`<hello xmlns="` + baseNamespace + `"><capabilities><capability>urn:ietf:params:netconf:base:2.0</capability></capabilities></hello>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = <-result
+	if err == nil || err.Error() != "the client supports no common NETCONF version" {
+		t.Errorf("Serve: %v", err)
+	}
+}
+
+func TestServeEditConfig(t *testing.T) {
+	conn := sysrepotest.New(t, testSchema)
+	session := sysrepotest.Session(t, conn, sysrepo.DSRunning)
+	c := connect(t, NewServer(conn), base11)
+	mtu := "/sysrepo-test:interfaces/interface[name='eth0']/mtu"
+
+	editConfig := func(errorOption string, config string) *element {
+		return c.rpc(`<edit-config><target><running/></target><error-option>` + errorOption + `</error-option><config>` + config + `</config></edit-config>`)
+	}
+
+	expectOK(t, editConfig("stop-on-error", `<interfaces xmlns="`+testNamespace+`"><interface><name>eth0</name><mtu>1500</mtu></interface></interfaces>`))
+	value, err := session.GetItem(mtu)
+	if err != nil || value != "1500" {
+		t.Errorf("mtu %s, %v, want 1500", value, err)
+	}
+
+	reply := c.rpc(`<get-config><source><running/></source><filter type="subtree"><interfaces xmlns="` + testNamespace + `"><interface><name>eth0</name></interface></interfaces></filter></get-config>`)
+	got := descendant(reply.child(baseNamespace, "data"), testNamespace, "interfaces", "interface", "mtu")
+	if got == nil || got.value() != "1500" {
+		t.Errorf("get-config %s, want mtu 1500", reply.content())
+	}
+
+	expectError(t, editConfig("continue-on-error", `<interfaces xmlns="`+testNamespace+`"><interface><name>eth0</name><mtu>9000</mtu></interface></interfaces>`), "operation-not-supported")
+	value, err = session.GetItem(mtu)
+	if err != nil || value != "1500" {
+		t.Errorf("mtu %s, %v, want 1500", value, err)
+	}
+
+	// The operation attribute uses the prefix declared on the rpc element.
+	expectOK(t, editConfig("rollback-on-error", `<interfaces xmlns="`+testNamespace+`"><interface nc:operation="delete"><name>eth0</name></interface></interfaces>`))
+	_, err = session.GetItem(mtu)
+	var srErr sysrepo.Error
+	if !errors.As(err, &srErr) || srErr.Code != sysrepo.ErrNotFound {
+		t.Errorf("GetItem(%s) = %v, want ErrNotFound", mtu, err)
+	}
+}
+
+func TestServeLock(t *testing.T) {
+	server := NewServer(sysrepotest.New(t, testSchema))
+	first := connect(t, server, base11)
+	second := connect(t, server, base11)
+	lock := `<lock><target><running/></target></lock>`
+	unlock := `<unlock><target><running/></target></unlock>`
+
+	if id := descendant(second.hello, baseNamespace, "session-id"); id == nil || id.value() != "2" {
+		t.Errorf("hello %s, want session-id 2", second.hello.content())
+	}
+
+	expectOK(t, first.rpc(lock))
+
+	// Both the holder and the other session are denied the lock, and told
+	// who holds it.
+	for _, c := range []*client{first, second} {
+		rpcErr := expectError(t, c.rpc(lock), "lock-denied")
+		holder := descendant(rpcErr, baseNamespace, "error-info", "session-id")
+		if holder == nil || holder.value() != "1" {
+			t.Errorf("lock-denied %s, want session-id 1", rpcErr.content())
+		}
+	}
+
+	expectError(t, second.rpc(unlock), "operation-failed")
+	expectOK(t, first.rpc(unlock))
+	expectOK(t, second.rpc(lock))
+}
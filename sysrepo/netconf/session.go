@@ -0,0 +1,488 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+const (
+	baseNamespace = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	nmdaNamespace = "urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"
+	yangNamespace = "urn:ietf:params:xml:ns:yang:1"
+
+	base10 = "urn:ietf:params:netconf:base:1.0"
+	base11 = "urn:ietf:params:netconf:base:1.1"
+)
+
+var capabilities = []string{
+	base10,
+	base11,
+	"urn:ietf:params:netconf:capability:writable-running:1.0",
+	"urn:ietf:params:netconf:capability:candidate:1.0",
+	"urn:ietf:params:netconf:capability:startup:1.0",
+	"urn:ietf:params:netconf:capability:rollback-on-error:1.0",
+	"urn:ietf:params:netconf:capability:xpath:1.0",
+}
+
+// session is a NETCONF session of a client.
+type session struct {
+	server    *Server
+	id        uint32
+	transport *transport
+	sr        *sysrepo.Session
+	locks     map[sysrepo.Datastore]*sysrepo.Lock
+	modules   map[string]string // Module names by namespace
+}
+
+func (s *session) run() error {
+	err := s.hello()
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := s.transport.readMessage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		reply, closing := s.handle(msg)
+		err = s.transport.writeMessage(reply)
+		if err != nil || closing {
+			return err
+		}
+	}
+}
+
+// close releases the locks of the session.
+func (s *session) close() {
+	for _, lock := range s.locks {
+		lock.Unlock()
+	}
+	s.server.sessions.Delete(s.sr.GetId())
+	s.sr.Close()
+}
+
+// hello exchanges the capabilities and switches to the chunked framing if
+// the client supports it.
+func (s *session) hello() error {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><hello xmlns="` + baseNamespace + `"><capabilities>`)
+	for _, capability := range capabilities {
+		b.WriteString("<capability>" + escape(capability) + "</capability>")
+	}
+	yangLibrary := "urn:ietf:params:netconf:capability:yang-library:1.1?revision=2019-01-04&content-id=" + strconv.FormatUint(uint64(s.server.conn.ContentID()), 10)
+	b.WriteString("<capability>" + escape(yangLibrary) + "</capability>")
+	b.WriteString("</capabilities><session-id>" + strconv.FormatUint(uint64(s.id), 10) + "</session-id></hello>")
+
+	err := s.transport.writeMessage([]byte(b.String()))
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.transport.readMessage()
+	if err != nil {
+		return err
+	}
+
+	hello, err := parseElement(msg)
+	if err != nil || hello.name.Space != baseNamespace || hello.name.Local != "hello" || hello.child(baseNamespace, "capabilities") == nil {
+		return errors.New("invalid hello message")
+	}
+
+	var supports10, supports11 bool
+	for _, capability := range hello.child(baseNamespace, "capabilities").children {
+		switch capability.value() {
+		case base10:
+			supports10 = true
+		case base11:
+			supports11 = true
+		}
+	}
+	if !supports10 && !supports11 {
+		return errors.New("the client supports no common NETCONF version")
+	}
+
+	s.transport.chunked = supports11
+	return nil
+}
+
+// handle returns the reply to an rpc message, and whether the session is
+// closing.
+func (s *session) handle(msg []byte) ([]byte, bool) {
+	rpc, err := parseElement(msg)
+	if err != nil || rpc.name.Space != baseNamespace || rpc.name.Local != "rpc" {
+		return s.reply(nil, (&rpcError{Type: "rpc", Tag: "malformed-message", Message: "Expected an rpc message"}).xml()), false
+	}
+	if _, ok := rpc.attr("message-id"); !ok {
+		return s.reply(rpc, (&rpcError{Type: "rpc", Tag: "missing-attribute", Message: "The rpc has no message-id"}).xml()), false
+	}
+	if len(rpc.children) != 1 {
+		return s.reply(rpc, (&rpcError{Type: "rpc", Tag: "malformed-message", Message: "Expected exactly one operation"}).xml()), false
+	}
+
+	content, closing, err := s.dispatch(rpc.children[0])
+	switch {
+	case err != nil:
+		content = s.replyError(err).xml()
+	case content == "":
+		content = "<ok/>"
+	}
+	return s.reply(rpc, content), closing
+}
+
+func (s *session) reply(rpc *element, content string) []byte {
+	var b strings.Builder
+	b.WriteString("<rpc-reply")
+	writeAttr(&b, "xmlns", baseNamespace)
+	if rpc != nil {
+		for _, attr := range rpc.attrs {
+			if attr.Name.Space == "" {
+				writeAttr(&b, attr.Name.Local, attr.Value)
+			}
+		}
+	}
+	b.WriteString(">" + content + "</rpc-reply>")
+	return []byte(b.String())
+}
+
+// dispatch runs an operation and returns the content of its reply, empty for
+// <ok/>.
+func (s *session) dispatch(op *element) (string, bool, error) {
+	var content string
+	var err error
+	switch op.name {
+	case baseName("get"):
+		content, err = s.get(op)
+	case baseName("get-config"):
+		content, err = s.getConfig(op)
+	case baseName("edit-config"):
+		err = s.editConfig(op)
+	case baseName("copy-config"):
+		err = s.copyConfig(op)
+	case baseName("lock"):
+		err = s.lock(op)
+	case baseName("unlock"):
+		err = s.unlock(op)
+	case baseName("commit"):
+		err = s.commit(op)
+	case baseName("discard-changes"):
+		err = s.copy(sysrepo.DSRunning, sysrepo.DSCandidate)
+	case baseName("close-session"):
+		return "", true, nil
+	case nmdaName("get-data"):
+		content, err = s.getData(op)
+	default:
+		if op.name.Space == baseNamespace || op.name.Space == yangNamespace {
+			err = notSupported(op.name.Local)
+		} else {
+			content, err = s.rpc(op)
+		}
+	}
+	return content, false, err
+}
+
+func (s *session) get(op *element) (string, error) {
+	selection, err := s.filterXpath(op.child(baseNamespace, "filter"))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := s.export(sysrepo.DSOperational, selection, sysrepo.GetDefault)
+	return "<data>" + data + "</data>", err
+}
+
+func (s *session) getConfig(op *element) (string, error) {
+	source, err := s.datastoreParam(op, "source")
+	if err != nil {
+		return "", err
+	}
+
+	selection, err := s.filterXpath(op.child(baseNamespace, "filter"))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := s.export(source, selection, sysrepo.GetDefault)
+	return "<data>" + data + "</data>", err
+}
+
+// getData implements get-data of RFC 8526 without the origin filters and
+// max-depth.
+func (s *session) getData(op *element) (string, error) {
+	param := op.child(nmdaNamespace, "datastore")
+	if param == nil {
+		return "", missingElement("datastore")
+	}
+
+	_, identity, _ := strings.Cut(param.value(), ":")
+	ds, ok := map[string]sysrepo.Datastore{
+		"running":         sysrepo.DSRunning,
+		"candidate":       sysrepo.DSCandidate,
+		"startup":         sysrepo.DSStartup,
+		"operational":     sysrepo.DSOperational,
+		"factory-default": sysrepo.DSFactoryDefault,
+	}[identity]
+	if !ok {
+		return "", &rpcError{Type: "application", Tag: "invalid-value", Message: "Unknown datastore '" + param.value() + "'"}
+	}
+
+	selection := "/*"
+	var err error
+	if filter := op.child(nmdaNamespace, "subtree-filter"); filter != nil {
+		selection, err = s.subtreeXpath(filter)
+	} else if filter := op.child(nmdaNamespace, "xpath-filter"); filter != nil {
+		selection, err = s.xpathModules(filter.value(), filter.scope())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	opts := sysrepo.GetDefault
+	for _, child := range op.children {
+		switch child.name.Local {
+		case "datastore", "subtree-filter", "xpath-filter":
+		case "config-filter":
+			if child.value() == "true" {
+				opts |= sysrepo.GetOperNoState
+			} else {
+				opts |= sysrepo.GetOperNoConfig
+			}
+		case "with-origin":
+			if ds != sysrepo.DSOperational {
+				return "", &rpcError{Type: "application", Tag: "invalid-value", Message: "with-origin requires the operational datastore"}
+			}
+			opts |= sysrepo.GetOperWithOrigin
+		default:
+			return "", notSupported(child.name.Local)
+		}
+	}
+
+	data, err := s.export(ds, selection, opts)
+	return `<data xmlns="` + nmdaNamespace + `">` + data + "</data>", err
+}
+
+func (s *session) export(ds sysrepo.Datastore, selection string, opts sysrepo.GetOptions) (string, error) {
+	err := s.sr.SwitchDatastore(ds)
+	if err != nil {
+		return "", err
+	}
+	return s.sr.ExportData(selection, sysrepo.FormatXML, opts, s.server.Timeout)
+}
+
+func (s *session) editConfig(op *element) error {
+	target, err := s.datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+	if target == sysrepo.DSStartup {
+		return &rpcError{Type: "protocol", Tag: "invalid-value", Message: "The startup datastore can only be copied to"}
+	}
+
+	defaultOperation := sysrepo.OpMerge
+	if param := op.child(baseNamespace, "default-operation"); param != nil {
+		defaultOperation = sysrepo.DefaultOperation(param.value())
+		if defaultOperation != sysrepo.OpMerge && defaultOperation != sysrepo.OpReplace && defaultOperation != sysrepo.OpNone {
+			return &rpcError{Type: "protocol", Tag: "invalid-value", Message: "Unknown default operation '" + param.value() + "'"}
+		}
+	}
+	if param := op.child(baseNamespace, "test-option"); param != nil && param.value() == "test-only" {
+		return notSupported("test-only")
+	}
+	// Sysrepo applies the edit as a whole, so stopping on an error rolls back
+	// the changes made before it as well.
+	if param := op.child(baseNamespace, "error-option"); param != nil {
+		switch param.value() {
+		case "stop-on-error", "rollback-on-error":
+		case "continue-on-error":
+			return notSupported(param.value())
+		default:
+			return &rpcError{Type: "protocol", Tag: "invalid-value", Message: "Unknown error option '" + param.value() + "'"}
+		}
+	}
+
+	config := op.child(baseNamespace, "config")
+	if config == nil {
+		return missingElement("config")
+	}
+
+	err = s.sr.SwitchDatastore(target)
+	if err == nil {
+		err = s.sr.EditBatch(config.content(), sysrepo.FormatXML, defaultOperation)
+	}
+	if err == nil {
+		err = s.sr.ApplyChanges(s.server.Timeout)
+	}
+	if err != nil {
+		s.sr.DiscardChanges(nil)
+	}
+	return err
+}
+
+func (s *session) copyConfig(op *element) error {
+	target, err := s.datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+
+	if param := op.child(baseNamespace, "source"); param != nil {
+		if config := param.child(baseNamespace, "config"); config != nil {
+			err = s.sr.SwitchDatastore(target)
+			if err != nil {
+				return err
+			}
+			return s.sr.ReplaceConfig(nil, config.content(), sysrepo.FormatXML, s.server.Timeout)
+		}
+	}
+
+	source, err := s.datastoreParam(op, "source")
+	if err != nil {
+		return err
+	}
+	if source == target {
+		return &rpcError{Type: "application", Tag: "invalid-value", Message: "The source and target are the same datastore"}
+	}
+	return s.copy(source, target)
+}
+
+// copy replaces the configuration of target with the one of source.
+func (s *session) copy(source sysrepo.Datastore, target sysrepo.Datastore) error {
+	err := s.sr.SwitchDatastore(target)
+	if err != nil {
+		return err
+	}
+	return s.sr.CopyConfig(source, nil, s.server.Timeout)
+}
+
+func (s *session) commit(op *element) error {
+	if op.child(baseNamespace, "confirmed") != nil {
+		return notSupported("confirmed commit")
+	}
+	return s.copy(sysrepo.DSCandidate, sysrepo.DSRunning)
+}
+
+func (s *session) lock(op *element) error {
+	target, err := s.datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+	if s.locks[target] != nil {
+		return lockDenied(s.id)
+	}
+
+	err = s.sr.SwitchDatastore(target)
+	if err != nil {
+		return err
+	}
+
+	lock, err := sysrepo.NewLock(s.sr, nil, nil)
+	var srErr sysrepo.Error
+	if errors.As(err, &srErr) && srErr.Code == sysrepo.ErrLocked {
+		return lockDenied(s.lockHolder(target))
+	}
+	if err != nil {
+		return err
+	}
+
+	s.locks[target] = lock
+	return nil
+}
+
+// lockHolder returns the NETCONF session ID of the holder of the lock of ds,
+// 0 if it is unknown or not a NETCONF session.
+func (s *session) lockHolder(ds sysrepo.Datastore) uint32 {
+	status, err := s.sr.LockInfo(ds, nil)
+	if err != nil || !status.Locked {
+		return 0
+	}
+
+	id, ok := s.server.sessions.Load(status.SessionID)
+	if !ok {
+		return 0
+	}
+	return id.(uint32)
+}
+
+func (s *session) unlock(op *element) error {
+	target, err := s.datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+
+	lock := s.locks[target]
+	if lock == nil {
+		return &rpcError{Type: "protocol", Tag: "operation-failed", Message: "The datastore is not locked by this session"}
+	}
+
+	delete(s.locks, target)
+	return lock.Unlock()
+}
+
+// rpc sends a YANG RPC to its sysrepo subscribers.
+func (s *session) rpc(op *element) (string, error) {
+	module, err := s.module(op.name.Space)
+	if err != nil {
+		return "", notSupported(op.name.Local)
+	}
+
+	path := "/" + module + ":" + op.name.Local
+	schema, err := s.server.conn.FindSchemaNode(path)
+	if err != nil || schema.Type != sysrepo.SchemaRPC {
+		return "", notSupported(op.name.Local)
+	}
+
+	input := `<input xmlns="` + escape(op.name.Space) + `">` + op.content() + "</input>"
+	return s.sr.RPCSend(path, input, sysrepo.FormatXML, sysrepo.FormatXML, s.server.Timeout)
+}
+
+// datastoreParam returns the configuration datastore of a parameter such as
+// <target><running/></target>.
+func (s *session) datastoreParam(op *element, name string) (sysrepo.Datastore, error) {
+	param := op.child(baseNamespace, name)
+	if param == nil {
+		return 0, missingElement(name)
+	}
+	if len(param.children) != 1 || param.children[0].name.Space != baseNamespace {
+		return 0, &rpcError{Type: "protocol", Tag: "invalid-value", Message: "Expected a datastore in " + name}
+	}
+
+	switch param.children[0].name.Local {
+	case "running":
+		return sysrepo.DSRunning, nil
+	case "candidate":
+		return sysrepo.DSCandidate, nil
+	case "startup":
+		return sysrepo.DSStartup, nil
+	}
+	return 0, notSupported(param.children[0].name.Local)
+}
+
+// module returns the name of the module with the namespace.
+func (s *session) module(namespace string) (string, error) {
+	if module, ok := s.modules[namespace]; ok {
+		return module, nil
+	}
+
+	module, err := s.server.conn.ModuleByNamespace(namespace)
+	if err != nil {
+		return "", &rpcError{Type: "application", Tag: "unknown-namespace", Message: "Unknown namespace '" + namespace + "'"}
+	}
+	s.modules[namespace] = module
+	return module, nil
+}
+
+func baseName(local string) xml.Name {
+	return xml.Name{Space: baseNamespace, Local: local}
+}
+
+func nmdaName(local string) xml.Name {
+	return xml.Name{Space: nmdaNamespace, Local: local}
+}
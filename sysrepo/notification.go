@@ -0,0 +1,158 @@
+package sysrepo
+
+/*
+ #cgo LDFLAGS: -lsysrepo
+ #include <stdlib.h>
+ #include <sysrepo.h>
+
+ extern void goNotifCallback(sr_session_ctx_t *session, uint32_t sub_id, sr_ev_notif_type_t notif_type, struct lyd_node *notif, struct timespec *timestamp, void *private_data);
+*/
+import "C"
+import (
+	"context"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+)
+
+// NotificationCallback is called for every notification of a subscription.
+// The session is the implicit event session, valid only during the call. The
+// notification tree is a copy owned by the callback, empty for the
+// subscription state types such as NotifReplayComplete.
+type NotificationCallback func(session *Session, notifType NotificationType, notif Notification)
+
+type notifSubscriber struct {
+	session  *Session
+	callback NotificationCallback
+}
+
+// NotificationSubscribe subscribes to the notifications of moduleName,
+// optionally filtered by xpath. If start is set, stored notifications since
+// then are replayed first. If stop is set, the subscription terminates then.
+func (s *Session) NotificationSubscribe(moduleName string, xpath *string, start *time.Time, stop *time.Time, callback NotificationCallback, opts SubscribeOptions) (*Subscription, error) {
+	moduleNameC, freeModule := stringToC(moduleName)
+	defer freeModule()
+
+	var xpathC *C.char
+	var free func()
+	if xpath != nil {
+		xpathC, free = stringToC(*xpath)
+		defer free()
+	}
+
+	data, freeData := newCallbackData(&notifSubscriber{
+		session:  s,
+		callback: callback,
+	})
+
+	var sub *C.sr_subscription_ctx_t
+	rc := C.sr_notif_subscribe_tree(s.sess, moduleNameC, xpathC, timeToC(start), timeToC(stop),
+		(C.sr_event_notif_tree_cb)(unsafe.Pointer(C.goNotifCallback)), data, C.sr_subscr_options_t(opts), &sub)
+	if rc != C.SR_ERR_OK {
+		freeData()
+		return nil, Error{
+			Message: "Couldn't subscribe to notifications of '" + moduleName + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	subscription := newSubscription(s, sub)
+	subscription.cleanupTasks = append(subscription.cleanupTasks, freeData)
+	return subscription, nil
+}
+
+// ReplayNotifications returns the stored notifications of moduleName between
+// start and stop, or until now if stop is nil. The channel is closed once the
+// replay is complete or ctx is done. Replay support must be enabled for the
+// module.
+func (s *Session) ReplayNotifications(ctx context.Context, moduleName string, xpath *string, start time.Time, stop *time.Time) (<-chan Notification, error) {
+	replay, err := s.conn.GetModuleReplaySupport(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	if !replay.Enabled {
+		return nil, Error{
+			Message: "Replay is not enabled for module '" + moduleName + "'",
+			Code:    ErrUnsupported,
+		}
+	}
+
+	notifs := make(chan Notification)
+	finished := make(chan struct{})
+	var mu sync.Mutex
+	closed := false
+
+	callback := func(_ *Session, notifType NotificationType, notif Notification) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if closed || notifType != NotifReplay {
+			if notif.Tree.Ptr != nil {
+				notif.Tree.Free()
+			}
+		}
+		if closed {
+			return
+		}
+
+		switch notifType {
+		case NotifReplay:
+			select {
+			case notifs <- notif:
+			case <-ctx.Done():
+				notif.Tree.Free()
+			}
+		case NotifReplayComplete, NotifTerminated:
+			closed = true
+			close(notifs)
+			close(finished)
+		}
+	}
+
+	sub, err := s.NotificationSubscribe(moduleName, xpath, &start, stop, callback, SubsDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-finished:
+		case <-ctx.Done():
+		}
+		sub.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(notifs)
+		}
+	}()
+
+	return notifs, nil
+}
+
+//export goNotifCallback
+func goNotifCallback(session *C.sr_session_ctx_t, subID C.uint32_t, notifType C.sr_ev_notif_type_t, notif *C.struct_lyd_node, timestamp *C.struct_timespec, privateData unsafe.Pointer) {
+	subscriber := callbackData(privateData).(*notifSubscriber)
+
+	var tree *C.struct_lyd_node
+	if notif != nil {
+		C.lyd_dup_single(notif, nil, C.LYD_DUP_RECURSIVE|C.LYD_DUP_WITH_PARENTS, &tree)
+		for tree != nil && tree.parent != nil {
+			tree = (*C.struct_lyd_node)(unsafe.Pointer(tree.parent))
+		}
+	}
+
+	var ts time.Time
+	if timestamp != nil {
+		ts = timeFromC(*timestamp)
+	}
+
+	subscriber.callback(eventSession(session, subscriber.session), NotificationType(notifType), Notification{
+		Tree:      libyang.NewNode(unsafe.Pointer(tree)),
+		Timestamp: ts,
+	})
+}
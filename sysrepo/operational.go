@@ -0,0 +1,146 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+import (
+	"time"
+	"unsafe"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+)
+
+// OperPusher pushes data into the operational datastore. Pushed data is stored
+// by sysrepo per session until it is discarded or the session stops.
+type OperPusher struct {
+	session *Session
+}
+
+// NewOperPusher creates a pusher on top of a session on the operational
+// datastore.
+func NewOperPusher(session *Session) (*OperPusher, error) {
+	if session.ActiveDatastore() != DSOperational {
+		return nil, Error{
+			Message: "Session is not on the operational datastore",
+			Code:    ErrInvalArg,
+		}
+	}
+
+	return &OperPusher{session: session}, nil
+}
+
+// Set prepares setting the node at path to value with an optional origin,
+// e.g. "ietf-origin:learned".
+func (p *OperPusher) Set(path string, value *string, origin *string, opts EditOptions) error {
+	pathC, freePath := stringToC(path)
+	defer freePath()
+
+	var valueC *C.char
+	var freeValue func()
+	if value != nil {
+		valueC, freeValue = stringToC(*value)
+		defer freeValue()
+	}
+
+	var originC *C.char
+	var freeOrigin func()
+	if origin != nil {
+		originC, freeOrigin = stringToC(*origin)
+		defer freeOrigin()
+	}
+
+	rc := C.sr_set_item_str(p.session.sess, pathC, valueC, originC, C.uint(opts))
+	return throwIfError(rc, "Couldn't set operational '"+path+"'")
+}
+
+// Delete prepares deleting the node at path from the operational datastore,
+// including nodes provided by other sessions. Value selects a leaf-list
+// instance.
+func (p *OperPusher) Delete(path string, value *string, opts EditOptions) error {
+	pathC, freePath := stringToC(path)
+	defer freePath()
+
+	var valueC *C.char
+	var freeValue func()
+	if value != nil {
+		valueC, freeValue = stringToC(*value)
+		defer freeValue()
+	}
+
+	rc := C.sr_oper_delete_item_str(p.session.sess, pathC, valueC, C.uint(opts))
+	return throwIfError(rc, "Couldn't delete operational '"+path+"'")
+}
+
+// Apply stores the prepared changes in the operational datastore.
+func (p *OperPusher) Apply(timeout time.Duration) error {
+	return p.session.ApplyChanges(timeout)
+}
+
+// Pushed returns the data stored by this session for moduleName, or for all
+// modules if it is nil. The returned tree is owned by the caller and empty if
+// nothing is stored.
+func (p *OperPusher) Pushed(moduleName *string) (libyang.DataNode, error) {
+	var moduleNameC *C.char
+	var free func()
+	if moduleName != nil {
+		moduleNameC, free = stringToC(*moduleName)
+		defer free()
+	}
+
+	var data *C.sr_data_t
+	rc := C.sr_get_oper_changes(p.session.sess, moduleNameC, &data)
+	if rc != C.SR_ERR_OK {
+		return libyang.NewNode(nil), Error{
+			Message: "Couldn't get stored operational changes",
+			Code:    ErrorCode(rc),
+		}
+	}
+	if data == nil {
+		return libyang.NewNode(nil), nil
+	}
+	defer C.sr_release_data(data)
+
+	var dup *C.struct_lyd_node
+	if data.tree != nil {
+		lyrc := C.lyd_dup_siblings(data.tree, nil, C.LYD_DUP_RECURSIVE|C.LYD_DUP_WITH_FLAGS, &dup)
+		if lyrc != C.LY_SUCCESS {
+			return libyang.NewNode(nil), Error{
+				Message: "Couldn't copy stored operational changes",
+				Code:    ErrLibyang,
+			}
+		}
+	}
+
+	return libyang.NewNode(unsafe.Pointer(dup)), nil
+}
+
+// Clear discards all the data stored by this session under xpath, or all of
+// it if xpath is empty.
+func (p *OperPusher) Clear(xpath string, timeout time.Duration) error {
+	return p.session.conn.DiscardOperationalChanges(xpath, p.session, timeout)
+}
+
+// OperPollSubscribe makes sysrepo poll the operational data provider of path
+// and cache its data for the valid duration, so that reads without
+// GetOperNoPollSubscriptionsCached are served from the cache. With
+// SubsOperPollDiff, module change events are generated on the operational
+// datastore whenever the polled data differs from the cached data.
+func (s *Session) OperPollSubscribe(moduleName string, path string, valid time.Duration, opts SubscribeOptions) (*Subscription, error) {
+	moduleNameC, freeModule := stringToC(moduleName)
+	defer freeModule()
+
+	pathC, freePath := stringToC(path)
+	defer freePath()
+
+	var sub *C.sr_subscription_ctx_t
+	rc := C.sr_oper_poll_subscribe(s.sess, moduleNameC, pathC, C.uint32_t(valid/time.Millisecond), C.sr_subscr_options_t(opts), &sub)
+	if rc != C.SR_ERR_OK {
+		return nil, Error{
+			Message: "Couldn't subscribe to poll '" + path + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	return newSubscription(s, sub), nil
+}
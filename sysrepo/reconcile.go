@@ -0,0 +1,213 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+import (
+	"errors"
+	"time"
+	"unsafe"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+)
+
+type ReconcileOptions struct {
+	// Timeout of reading the current data and of applying the changes.
+	Timeout time.Duration
+	// DryRun only computes the difference without applying it.
+	DryRun bool
+}
+
+// Reconcile makes the configuration of moduleName in the active datastore
+// equal to desired, which must only hold data of that module, using the
+// minimal set of create, modify, delete and move edits. It returns the applied
+// difference as a libyang diff tree owned by the caller, empty if the
+// configuration already matched, in which case nothing is applied and no
+// subscriber is notified. Unless it is a dry run, it fails if the session has
+// edits prepared but not applied.
+func (s *Session) Reconcile(moduleName string, desired libyang.DataNode, opts ReconcileOptions) (libyang.DataNode, error) {
+	if !opts.DryRun && s.HasChanges() {
+		return libyang.NewNode(nil), Error{
+			Message: "The session has edits prepared outside of the reconciliation",
+			Code:    ErrInvalArg,
+		}
+	}
+
+	xpathC, freeXpath := stringToC("/" + moduleName + ":*")
+	defer freeXpath()
+
+	var data *C.sr_data_t
+	rc := C.sr_get_data(s.sess, xpathC, 0, C.uint(opts.Timeout/time.Millisecond), 0, &data)
+	if rc != C.SR_ERR_OK {
+		return libyang.NewNode(nil), Error{
+			Message: "Couldn't get current data of '" + moduleName + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	var current *C.struct_lyd_node
+	if data != nil {
+		current = data.tree
+		defer C.sr_release_data(data)
+	}
+
+	var diff *C.struct_lyd_node
+	lyrc := C.lyd_diff_siblings(current, nodeToC(desired), 0, &diff)
+	if lyrc != C.LY_SUCCESS {
+		return libyang.NewNode(nil), Error{
+			Message: "Couldn't compute the difference for '" + moduleName + "'",
+			Code:    ErrLibyang,
+		}
+	}
+
+	result := libyang.NewNode(unsafe.Pointer(diff))
+	if diff == nil || opts.DryRun {
+		return result, nil
+	}
+
+	err := s.applyDiff(diff, "none")
+	if err == nil {
+		err = s.ApplyChanges(opts.Timeout)
+	}
+	if err != nil {
+		return result, errors.Join(err, s.DiscardChanges(nil))
+	}
+
+	return result, nil
+}
+
+// applyDiff prepares the edits for the diff nodes and their siblings. Nodes
+// without an operation inherit the one of their parent.
+func (s *Session) applyDiff(node *C.struct_lyd_node, inherited string) error {
+	for ; node != nil; node = node.next {
+		if node.schema == nil {
+			continue
+		}
+
+		op := diffMeta(node, "yang:operation")
+		if op == "" {
+			op = inherited
+		}
+
+		var err error
+		switch op {
+		case "create":
+			err = s.diffCreate(node)
+		case "delete":
+			err = s.diffPathEdit(node, func(path string) error {
+				return s.DeleteItem(path, EditDefault)
+			})
+		case "replace":
+			if node.schema.nodetype == C.LYS_LEAF {
+				err = s.diffSet(node)
+			} else if node.schema.flags&C.LYS_ORDBY_USER != 0 {
+				err = s.diffMove(node)
+			}
+			if err == nil {
+				err = s.applyDiff(C.lyd_child(node), "none")
+			}
+		default:
+			err = s.applyDiff(C.lyd_child(node), "none")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffCreate creates a node with its whole subtree.
+func (s *Session) diffCreate(node *C.struct_lyd_node) error {
+	if node.schema.flags&C.LYS_KEY != 0 {
+		return nil // Created along with the list entry
+	}
+
+	err := s.diffSet(node)
+	if err == nil && node.schema.flags&C.LYS_ORDBY_USER != 0 {
+		err = s.diffMove(node)
+	}
+	if err != nil {
+		return err
+	}
+
+	for child := C.lyd_child(node); child != nil; child = child.next {
+		if child.schema == nil {
+			continue
+		}
+		err = s.diffCreate(child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Session) diffSet(node *C.struct_lyd_node) error {
+	return s.diffPathEdit(node, func(path string) error {
+		if node.schema.nodetype != C.LYS_LEAF {
+			return s.SetItem(path, nil, EditDefault)
+		}
+
+		value := C.GoString(C.lyd_get_value(node))
+		return s.SetItem(path, &value, EditDefault)
+	})
+}
+
+// diffMove moves a user-ordered list or leaf-list entry after the entry named
+// by its diff metadata, or first if that is empty.
+func (s *Session) diffMove(node *C.struct_lyd_node) error {
+	position := MoveAfter
+	var anchor string
+	if node.schema.nodetype == C.LYS_LIST {
+		anchor = diffMeta(node, "yang:key")
+	} else {
+		anchor = diffMeta(node, "yang:value")
+	}
+	if anchor == "" {
+		position = MoveFirst
+	}
+
+	return s.diffPathEdit(node, func(path string) error {
+		pathC, freePath := stringToC(path)
+		defer freePath()
+
+		anchorC, freeAnchor := stringToC(anchor)
+		defer freeAnchor()
+
+		var keys, value *C.char
+		if node.schema.nodetype == C.LYS_LIST {
+			keys = anchorC
+		} else {
+			value = anchorC
+		}
+
+		rc := C.sr_move_item(s.sess, pathC, C.sr_move_position_t(position), keys, value, nil, C.uint(EditDefault))
+		return throwIfError(rc, "Couldn't move '"+path+"'")
+	})
+}
+
+func (s *Session) diffPathEdit(node *C.struct_lyd_node, edit func(path string) error) error {
+	pathC := C.lyd_path(node, C.LYD_PATH_STD, nil, 0)
+	if pathC == nil {
+		return Error{
+			Message: "Couldn't get the path of a changed node",
+			Code:    ErrNoMemory,
+		}
+	}
+	defer C.free(unsafe.Pointer(pathC))
+
+	return edit(C.GoString(pathC))
+}
+
+// diffMeta returns the value of the named metadata of a diff node.
+func diffMeta(node *C.struct_lyd_node, name string) string {
+	nameC, free := stringToC(name)
+	defer free()
+
+	meta := C.lyd_find_meta(node.meta, nil, nameC)
+	if meta == nil {
+		return ""
+	}
+	return C.GoString(C.lyd_get_meta_value(meta))
+}
@@ -0,0 +1,226 @@
+package sysrepo_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepotest"
+)
+
+const testModule = "sysrepo-test"
+
+// changeRecorder records the changes of sysrepo-test reported in change
+// events, as "operation path[ after previous]".
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (r *changeRecorder) subscribe(t *testing.T, session *sysrepo.Session) {
+	t.Helper()
+
+	sub, err := session.ModuleChangeSubscribe(testModule, nil, func(session *sysrepo.Session, _ uint32, _ string, _ *string, event sysrepo.Event, _ uint32) error {
+		if event != sysrepo.EvChange {
+			return nil
+		}
+
+		iter, err := session.GetChanges("/" + testModule + ":*//.").Begin()
+		if err != nil {
+			return err
+		}
+		defer iter.Close()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for ; iter.HasNext(); err = iter.Next() {
+			if err != nil {
+				return err
+			}
+
+			change := iter.Current()
+			formatted := map[sysrepo.ChangeOperation]string{
+				sysrepo.OpCreated:  "created",
+				sysrepo.OpModified: "modified",
+				sysrepo.OpDeleted:  "deleted",
+				sysrepo.OpMoved:    "moved",
+			}[change.Operation] + " " + change.Path
+			if change.Operation == sysrepo.OpMoved && change.PreviousList != nil {
+				formatted += " after " + *change.PreviousList
+			}
+			r.changes = append(r.changes, formatted)
+		}
+		return nil
+	}, 0, sysrepo.SubsDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sub.Close)
+}
+
+func (r *changeRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := r.changes
+	r.changes = nil
+	return result
+}
+
+// reconcileSessions returns a running session with users a, b and c, and a
+// candidate session whose datastore starts with the same data.
+func reconcileSessions(t *testing.T) (*sysrepo.Session, *sysrepo.Session) {
+	t.Helper()
+
+	conn := sysrepotest.New(t, "testdata/sysrepo-test.yang")
+	running := sysrepotest.Session(t, conn, sysrepo.DSRunning)
+	err := running.SetFrom("/sysrepo-test:system", testSystem{
+		Hostname:   ptr("r1"),
+		DNSServers: []string{"10.0.0.1", "10.0.0.2"},
+		Users:      []testUser{{Name: "a"}, {Name: "b", UID: ptr(uint32(1001))}, {Name: "c"}},
+	})
+	if err == nil {
+		err = running.ApplyChanges(0)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return running, sysrepotest.Session(t, conn, sysrepo.DSCandidate)
+}
+
+func moduleData(t *testing.T, session *sysrepo.Session) libyang.DataNode {
+	t.Helper()
+
+	data, err := session.GetData("/"+testModule+":*", 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(data.Free)
+	return data
+}
+
+func TestReconcileNoChange(t *testing.T) {
+	running, candidate := reconcileSessions(t)
+	recorder := &changeRecorder{}
+	recorder.subscribe(t, running)
+
+	diff, err := running.Reconcile(testModule, moduleData(t, candidate), sysrepo.ReconcileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Ptr != nil {
+		diff.Free()
+		t.Error("Reconcile() of equal data returned a diff")
+	}
+	if changes := recorder.take(); len(changes) != 0 {
+		t.Errorf("Reconcile() of equal data notified changes:\n%s", strings.Join(changes, "\n"))
+	}
+}
+
+func TestReconcileMove(t *testing.T) {
+	running, candidate := reconcileSessions(t)
+	err := candidate.MoveItem("/sysrepo-test:system/user[name='c']", sysrepo.MoveFirst, nil, nil, sysrepo.EditDefault)
+	if err == nil {
+		err = candidate.ApplyChanges(0)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &changeRecorder{}
+	recorder.subscribe(t, running)
+
+	diff, err := running.Reconcile(testModule, moduleData(t, candidate), sysrepo.ReconcileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff.Free()
+
+	want := []string{"moved /sysrepo-test:system/user[name='c'] after "}
+	if changes := recorder.take(); !reflect.DeepEqual(changes, want) {
+		t.Errorf("Reconcile() changes:\n%s\nwant:\n%s", strings.Join(changes, "\n"), strings.Join(want, "\n"))
+	}
+
+	var system testSystem
+	err = running.GetInto("/sysrepo-test:system", &system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []string
+	for _, user := range system.Users {
+		users = append(users, user.Name)
+	}
+	if !reflect.DeepEqual(users, []string{"c", "a", "b"}) {
+		t.Errorf("users after Reconcile() = %v, want [c a b]", users)
+	}
+}
+
+func TestReconcileEdits(t *testing.T) {
+	running, candidate := reconcileSessions(t)
+	desired := testSystem{
+		Hostname:   ptr("r2"),
+		DNSServers: []string{"10.0.0.3", "10.0.0.2"},
+		NTP:        &testNTP{Server: "pool.ntp.org"},
+		Users:      []testUser{{Name: "d", UID: ptr(uint32(1003)), Admin: true}, {Name: "a"}, {Name: "c"}},
+	}
+	err := candidate.DeleteItem("/sysrepo-test:system", sysrepo.EditDefault)
+	if err == nil {
+		err = candidate.ApplyChanges(0)
+	}
+	if err == nil {
+		err = candidate.SetFrom("/sysrepo-test:system", desired)
+	}
+	if err == nil {
+		err = candidate.ApplyChanges(0)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dryRun, err := running.Reconcile(testModule, moduleData(t, candidate), sysrepo.ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dryRun.Ptr == nil {
+		t.Error("dry run Reconcile() returned no diff")
+	}
+	dryRun.Free()
+
+	diff, err := running.Reconcile(testModule, moduleData(t, candidate), sysrepo.ReconcileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff.Free()
+
+	var system testSystem
+	err = running.GetInto("/sysrepo-test:system", &system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(system, desired) {
+		t.Errorf("data after Reconcile() = %+v, want %+v", system, desired)
+	}
+}
+
+func TestReconcilePendingEdits(t *testing.T) {
+	running, candidate := reconcileSessions(t)
+
+	err := running.SetItem("/sysrepo-test:system/hostname", ptr("pending"), sysrepo.EditDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = running.Reconcile(testModule, moduleData(t, candidate), sysrepo.ReconcileOptions{})
+	var srErr sysrepo.Error
+	if !errors.As(err, &srErr) || srErr.Code != sysrepo.ErrInvalArg {
+		t.Errorf("Reconcile() with pending edits = %v, want ErrInvalArg", err)
+	}
+	if !running.HasChanges() {
+		t.Error("Reconcile() discarded the pending edits")
+	}
+}
@@ -0,0 +1,227 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+const (
+	mediaTypeJSON = "application/yang-data+json"
+	mediaTypeXML  = "application/yang-data+xml"
+
+	restconfModule    = "ietf-restconf"
+	restconfNamespace = "urn:ietf:params:xml:ns:yang:ietf-restconf"
+)
+
+// responseFormat returns the format accepted by the client, JSON unless it
+// asks for XML.
+func responseFormat(r *http.Request) sysrepo.DataFormat {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accepted))
+		switch mediaType {
+		case mediaTypeJSON, "application/json":
+			return sysrepo.FormatJSON
+		case mediaTypeXML, "application/xml", "text/xml":
+			return sysrepo.FormatXML
+		}
+	}
+	return sysrepo.FormatJSON
+}
+
+// requestFormat returns the format of the message body.
+func requestFormat(r *http.Request) (sysrepo.DataFormat, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return sysrepo.FormatJSON, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case mediaTypeJSON, "application/json":
+		return sysrepo.FormatJSON, nil
+	case mediaTypeXML, "application/xml", "text/xml":
+		return sysrepo.FormatXML, nil
+	}
+	return 0, newError(http.StatusUnsupportedMediaType, "invalid-value", "Unsupported media type '"+contentType+"'")
+}
+
+func mediaType(format sysrepo.DataFormat) string {
+	if format == sysrepo.FormatXML {
+		return mediaTypeXML
+	}
+	return mediaTypeJSON
+}
+
+// wrap encloses printed data in the element name of module, such as
+// ietf-restconf:data or the output of an operation.
+func wrap(data string, format sysrepo.DataFormat, module string, namespace string, name string) string {
+	if format == sysrepo.FormatXML {
+		return "<" + name + " xmlns=\"" + namespace + "\">" + data + "</" + name + ">"
+	}
+
+	if data == "" {
+		data = "{}"
+	}
+	return "{\"" + module + ":" + name + "\":" + data + "}"
+}
+
+// unwrapData returns the content of the ietf-restconf:data element enclosing
+// the datastore in a request body.
+func unwrapData(body string, format sysrepo.DataFormat) (string, error) {
+	invalid := newError(http.StatusBadRequest, "malformed-message", "Expected the ietf-restconf:data node")
+
+	if format == sysrepo.FormatJSON {
+		var wrapped map[string]json.RawMessage
+		err := json.Unmarshal([]byte(body), &wrapped)
+		if err != nil {
+			return "", invalid
+		}
+
+		data, ok := wrapped[restconfModule+":data"]
+		if !ok || len(wrapped) != 1 {
+			return "", invalid
+		}
+		return string(data), nil
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	start, end := int64(-1), int64(-1)
+	depth := 0
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", invalid
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				if token.Name.Space != restconfNamespace || token.Name.Local != "data" || start >= 0 {
+					return "", invalid
+				}
+				start = decoder.InputOffset()
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				end = offset
+			}
+		}
+	}
+	if start < 0 || end < start {
+		return "", invalid
+	}
+	return body[start:end], nil
+}
+
+// Error is a RESTCONF error reported to the client with an HTTP status and a
+// NETCONF error tag.
+type Error struct {
+	Status  int
+	Tag     string
+	Message string
+}
+
+func newError(status int, tag string, message string) *Error {
+	return &Error{Status: status, Tag: tag, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// errorFromSysrepo maps a sysrepo error to a RESTCONF error, using the
+// detailed messages of the session if there are any.
+func errorFromSysrepo(err error, session *sysrepo.Session) *Error {
+	var restconfErr *Error
+	if errors.As(err, &restconfErr) {
+		return restconfErr
+	}
+
+	result := newError(http.StatusInternalServerError, "operation-failed", err.Error())
+	if session != nil {
+		var messages []string
+		for _, info := range session.GetErrors() {
+			if info.Message != "" {
+				messages = append(messages, info.Message)
+			}
+		}
+		if len(messages) > 0 {
+			result.Message = strings.Join(messages, "; ")
+		}
+	}
+
+	var srErr sysrepo.Error
+	if !errors.As(err, &srErr) {
+		return result
+	}
+
+	switch srErr.Code {
+	case sysrepo.ErrNotFound:
+		result.Status, result.Tag = http.StatusNotFound, "invalid-value"
+	case sysrepo.ErrExists:
+		result.Status, result.Tag = http.StatusConflict, "data-exists"
+	case sysrepo.ErrUnauthorized:
+		result.Status, result.Tag = http.StatusForbidden, "access-denied"
+	case sysrepo.ErrLocked:
+		result.Status, result.Tag = http.StatusConflict, "lock-denied"
+	case sysrepo.ErrInvalArg, sysrepo.ErrLibyang, sysrepo.ErrValidationFailed:
+		result.Status, result.Tag = http.StatusBadRequest, "invalid-value"
+	case sysrepo.ErrUnsupported:
+		result.Status, result.Tag = http.StatusNotImplemented, "operation-not-supported"
+	}
+	return result
+}
+
+type xmlErrors struct {
+	XMLName xml.Name   `xml:"urn:ietf:params:xml:ns:yang:ietf-restconf errors"`
+	Errors  []xmlError `xml:"error"`
+}
+
+type xmlError struct {
+	Type    string `xml:"error-type"`
+	Tag     string `xml:"error-tag"`
+	Message string `xml:"error-message,omitempty"`
+}
+
+// write sends the error as an ietf-restconf:errors message.
+func (e *Error) write(w http.ResponseWriter, format sysrepo.DataFormat) {
+	errorType := "application"
+	if e.Tag == "malformed-message" || e.Status == http.StatusUnsupportedMediaType || e.Status == http.StatusMethodNotAllowed {
+		errorType = "protocol"
+	}
+
+	var body bytes.Buffer
+	if format == sysrepo.FormatXML {
+		xml.NewEncoder(&body).Encode(xmlErrors{
+			Errors: []xmlError{{Type: errorType, Tag: e.Tag, Message: e.Message}},
+		})
+	} else {
+		json.NewEncoder(&body).Encode(map[string]any{
+			restconfModule + ":errors": map[string]any{
+				"error": []map[string]string{{
+					"error-type":    errorType,
+					"error-tag":     e.Tag,
+					"error-message": e.Message,
+				}},
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", mediaType(format))
+	w.WriteHeader(e.Status)
+	w.Write(body.Bytes())
+}
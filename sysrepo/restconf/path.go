@@ -0,0 +1,69 @@
+package restconf
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/xpath"
+)
+
+// target is the data node addressed by a RESTCONF resource path.
+type target struct {
+	path   xpath.Path
+	schema *sysrepo.SchemaNode
+}
+
+// parseTarget converts the escaped path of a data resource below the
+// datastore, such as ietf-interfaces:interfaces/interface=eth0/mtu, into a
+// data path. List key values are matched with the key names of the schema.
+func (h *Handler) parseTarget(escaped string) (*target, error) {
+	t := &target{}
+	var schemaPath xpath.Path
+	for _, segment := range strings.Split(escaped, "/") {
+		escapedName, escapedValues, hasValues := strings.Cut(segment, "=")
+		name, err := url.PathUnescape(escapedName)
+		if err != nil || name == "" {
+			return nil, newError(http.StatusBadRequest, "invalid-value", "Invalid resource path segment '"+segment+"'")
+		}
+
+		t.path = t.path.Child(name)
+		schemaPath = schemaPath.Child(name)
+		if t.path[0].Module == "" {
+			return nil, newError(http.StatusBadRequest, "invalid-value", "The top-level node '"+name+"' has no module")
+		}
+
+		t.schema, err = h.conn.FindSchemaNode(schemaPath.String())
+		if err != nil {
+			return nil, newError(http.StatusNotFound, "invalid-value", "Unknown resource '"+schemaPath.String()+"'")
+		}
+
+		var values []string
+		if hasValues {
+			for _, escapedValue := range strings.Split(escapedValues, ",") {
+				value, err := url.PathUnescape(escapedValue)
+				if err != nil {
+					return nil, newError(http.StatusBadRequest, "invalid-value", "Invalid key value in '"+segment+"'")
+				}
+				// Data paths only take literals, which can't hold both quotes.
+				if _, err := xpath.QuoteLiteral(value); err != nil {
+					return nil, newError(http.StatusBadRequest, "invalid-value", "Unsupported key value in '"+segment+"': "+err.Error())
+				}
+				values = append(values, value)
+			}
+		}
+
+		switch {
+		case t.schema.Type == sysrepo.SchemaList && len(values) == len(t.schema.Keys):
+			for i, key := range t.schema.Keys {
+				t.path = t.path.Key(key, values[i])
+			}
+		case t.schema.Type == sysrepo.SchemaLeafList && len(values) == 1:
+			t.path = t.path.Value(values[0])
+		case t.schema.Type == sysrepo.SchemaList || hasValues:
+			return nil, newError(http.StatusBadRequest, "invalid-value", "Wrong number of key values in '"+segment+"'")
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,323 @@
+// Package restconf serves the data and operation resources of RESTCONF (RFC
+// 8040) from sysrepo datastores.
+//
+// Reading returns the combined configuration and state of the operational
+// datastore, edits are applied to running. Every request runs in its own
+// session whose NACM user is returned by the user function of the handler,
+// see Session.SetNacmUser for when sysrepo enforces its rules.
+//
+// Only the content query parameter is supported, and the Location header of
+// resources created by POST is not set.
+package restconf
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+const (
+	dataRoot       = "/restconf/data"
+	operationsRoot = "/restconf/operations"
+)
+
+// Handler serves the RESTCONF resources below /restconf.
+type Handler struct {
+	conn *sysrepo.Connection
+	user func(r *http.Request) (string, bool)
+	// Timeout of reading data, applying changes and operations, the sysrepo
+	// default if 0.
+	Timeout time.Duration
+}
+
+// NewHandler returns a handler using conn. The user function returns the
+// authenticated user of a request, which is rejected if there is none.
+func NewHandler(conn *sysrepo.Connection, user func(r *http.Request) (string, bool)) *Handler {
+	return &Handler{
+		conn: conn,
+		user: user,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format := responseFormat(r)
+
+	user, ok := h.user(r)
+	if !ok {
+		newError(http.StatusUnauthorized, "access-denied", "Authentication required").write(w, format)
+		return
+	}
+
+	var err error
+	path := r.URL.EscapedPath()
+	switch {
+	case path == dataRoot || path == dataRoot+"/":
+		err = h.serveData(w, r, user, nil)
+	case strings.HasPrefix(path, dataRoot+"/"):
+		var t *target
+		t, err = h.parseTarget(strings.TrimPrefix(path, dataRoot+"/"))
+		if err == nil {
+			err = h.serveData(w, r, user, t)
+		}
+	case strings.HasPrefix(path, operationsRoot+"/"):
+		err = h.serveOperation(w, r, user, strings.TrimPrefix(path, operationsRoot+"/"))
+	default:
+		err = newError(http.StatusNotFound, "invalid-value", "Unknown resource '"+path+"'")
+	}
+
+	if err != nil {
+		errorFromSysrepo(err, nil).write(w, format)
+	}
+}
+
+// session starts a session on datastore for the NACM user of a request.
+func (h *Handler) session(datastore sysrepo.Datastore, user string) (*sysrepo.Session, error) {
+	session, err := h.conn.SessionStart(datastore)
+	if err != nil {
+		return nil, err
+	}
+
+	err = session.SetNacmUser(user)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// serveData serves the datastore resource if t is nil, or the data resource
+// it addresses.
+func (h *Handler) serveData(w http.ResponseWriter, r *http.Request, user string, t *target) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return h.get(w, r, user, t)
+	case http.MethodPut:
+		return h.put(w, r, user, t)
+	case http.MethodPatch:
+		return h.patch(w, r, user, t)
+	case http.MethodPost:
+		if t != nil && t.schema.Type == sysrepo.SchemaAction {
+			return h.invoke(w, r, user, t.path.String(), t.schema)
+		}
+		return h.create(w, r, user, t)
+	case http.MethodDelete:
+		if t != nil {
+			return h.delete(w, r, user, t)
+		}
+	}
+
+	w.Header().Set("Allow", "GET, HEAD, PUT, PATCH, POST, DELETE")
+	return newError(http.StatusMethodNotAllowed, "operation-not-supported", "Method "+r.Method+" not allowed")
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, user string, t *target) error {
+	opts := sysrepo.GetDefault
+	for name, values := range r.URL.Query() {
+		if name != "content" {
+			return newError(http.StatusBadRequest, "invalid-value", "Unsupported query parameter '"+name+"'")
+		}
+		if len(values) != 1 {
+			return newError(http.StatusBadRequest, "invalid-value", "The query parameter '"+name+"' must appear once")
+		}
+
+		switch values[0] {
+		case "all":
+		case "config":
+			opts = sysrepo.GetOperNoState
+		case "nonconfig":
+			opts = sysrepo.GetOperNoConfig
+		default:
+			return newError(http.StatusBadRequest, "invalid-value", "Invalid content '"+values[0]+"'")
+		}
+	}
+
+	session, err := h.session(sysrepo.DSOperational, user)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	format := responseFormat(r)
+	var data string
+	if t == nil {
+		data, err = session.ExportData("/*", format, opts, h.Timeout)
+		data = wrap(data, format, restconfModule, restconfNamespace, "data")
+	} else {
+		data, err = session.ExportNode(t.path.String(), format, opts, h.Timeout)
+	}
+	if err != nil {
+		return errorFromSysrepo(err, session)
+	}
+	if data == "" {
+		return newError(http.StatusNotFound, "invalid-value", "No data at '"+t.path.String()+"'")
+	}
+
+	w.Header().Set("Content-Type", mediaType(format))
+	io.WriteString(w, data)
+	return nil
+}
+
+// put creates or replaces the target, or the whole configuration.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, user string, t *target) error {
+	return h.edit(w, r, user, func(session *sysrepo.Session, body string, format sysrepo.DataFormat) (int, error) {
+		if t == nil {
+			data, err := unwrapData(body, format)
+			if err != nil {
+				return 0, err
+			}
+			return http.StatusNoContent, session.ReplaceConfig(nil, data, format, h.Timeout)
+		}
+
+		exists, err := h.exists(session, t)
+		if err != nil {
+			return 0, err
+		}
+
+		status := http.StatusCreated
+		if exists {
+			status = http.StatusNoContent
+		}
+		path := t.path.String()
+		return status, h.apply(session, session.EditSubtree(t.path.Parent().String(), body, format, "replace", &path))
+	})
+}
+
+// patch merges the body into the existing target, or the whole configuration.
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, user string, t *target) error {
+	return h.edit(w, r, user, func(session *sysrepo.Session, body string, format sysrepo.DataFormat) (int, error) {
+		if t == nil {
+			data, err := unwrapData(body, format)
+			if err != nil {
+				return 0, err
+			}
+			return http.StatusNoContent, h.apply(session, session.EditBatch(data, format, sysrepo.OpMerge))
+		}
+
+		exists, err := h.exists(session, t)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, newError(http.StatusNotFound, "invalid-value", "No data at '"+t.path.String()+"'")
+		}
+		path := t.path.String()
+		return http.StatusNoContent, h.apply(session, session.EditSubtree(t.path.Parent().String(), body, format, "merge", &path))
+	})
+}
+
+// create creates the child resource of the target in the body.
+func (h *Handler) create(w http.ResponseWriter, r *http.Request, user string, t *target) error {
+	return h.edit(w, r, user, func(session *sysrepo.Session, body string, format sysrepo.DataFormat) (int, error) {
+		var parent string
+		if t != nil {
+			parent = t.path.String()
+		}
+		return http.StatusCreated, h.apply(session, session.EditSubtree(parent, body, format, "create", nil))
+	})
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, user string, t *target) error {
+	return h.edit(w, r, user, func(session *sysrepo.Session, body string, format sysrepo.DataFormat) (int, error) {
+		return http.StatusNoContent, h.apply(session, session.DeleteItem(t.path.String(), sysrepo.EditStrict))
+	})
+}
+
+// edit runs fn in a session on running with the request body and replies with
+// the status it returns.
+func (h *Handler) edit(w http.ResponseWriter, r *http.Request, user string, fn func(session *sysrepo.Session, body string, format sysrepo.DataFormat) (int, error)) error {
+	format, err := requestFormat(r)
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return newError(http.StatusBadRequest, "malformed-message", "Couldn't read the request body")
+	}
+
+	session, err := h.session(sysrepo.DSRunning, user)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	status, err := fn(session, string(body), format)
+	if err != nil {
+		return errorFromSysrepo(err, session)
+	}
+
+	w.WriteHeader(status)
+	return nil
+}
+
+// apply applies the changes prepared without err, or discards them.
+func (h *Handler) apply(session *sysrepo.Session, err error) error {
+	if err == nil {
+		err = session.ApplyChanges(h.Timeout)
+	}
+	if err != nil {
+		session.DiscardChanges(nil)
+	}
+	return err
+}
+
+func (h *Handler) exists(session *sysrepo.Session, t *target) (bool, error) {
+	data, err := session.ExportNode(t.path.String(), sysrepo.FormatXML, sysrepo.GetDefault, h.Timeout)
+	return data != "", err
+}
+
+// serveOperation invokes the RPC named by the escaped path, such as
+// ietf-system:system-restart.
+func (h *Handler) serveOperation(w http.ResponseWriter, r *http.Request, user string, escaped string) error {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		return newError(http.StatusMethodNotAllowed, "operation-not-supported", "Method "+r.Method+" not allowed")
+	}
+
+	t, err := h.parseTarget(escaped)
+	if err != nil {
+		return err
+	}
+	if t.schema.Type != sysrepo.SchemaRPC {
+		return newError(http.StatusNotFound, "invalid-value", "Unknown operation '"+t.path.String()+"'")
+	}
+	return h.invoke(w, r, user, t.path.String(), t.schema)
+}
+
+// invoke sends the RPC or action at path with the input in the request body
+// and replies with its output.
+func (h *Handler) invoke(w http.ResponseWriter, r *http.Request, user string, path string, schema *sysrepo.SchemaNode) error {
+	format, err := requestFormat(r)
+	if err != nil {
+		return err
+	}
+
+	input, err := io.ReadAll(r.Body)
+	if err != nil {
+		return newError(http.StatusBadRequest, "malformed-message", "Couldn't read the request body")
+	}
+
+	session, err := h.session(sysrepo.DSRunning, user)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	outputFormat := responseFormat(r)
+	output, err := session.RPCSend(path, string(input), format, outputFormat, h.Timeout)
+	if err != nil {
+		return errorFromSysrepo(err, session)
+	}
+
+	if output == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", mediaType(outputFormat))
+	io.WriteString(w, wrap(output, outputFormat, schema.Module, schema.Namespace, "output"))
+	return nil
+}
@@ -0,0 +1,225 @@
+package restconf_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/restconf"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepotest"
+)
+
+const eth0 = "/restconf/data/sysrepo-test:interfaces/interface=eth0"
+
+// server returns a handler on a test repository, authenticating the user in
+// the X-User header, and a session on its running datastore.
+func server(t *testing.T) (*restconf.Handler, *sysrepo.Session) {
+	t.Helper()
+
+	conn := sysrepotest.New(t, "../testdata/sysrepo-test.yang")
+	handler := restconf.NewHandler(conn, func(r *http.Request) (string, bool) {
+		user := r.Header.Get("X-User")
+		return user, user != ""
+	})
+	return handler, sysrepotest.Session(t, conn, sysrepo.DSRunning)
+}
+
+func request(t *testing.T, handler http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("X-User", "admin")
+	if body != "" {
+		r.Header.Set("Content-Type", "application/yang-data+json")
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+}
+
+func expectItem(t *testing.T, session *sysrepo.Session, path string, want string) {
+	t.Helper()
+
+	value, err := session.GetItem(path)
+	if err != nil {
+		t.Fatalf("GetItem(%s): %v", path, err)
+	}
+	if value != want {
+		t.Errorf("%s = %s, want %s", path, value, want)
+	}
+}
+
+func expectMissing(t *testing.T, session *sysrepo.Session, path string) {
+	t.Helper()
+
+	_, err := session.GetItem(path)
+	var srErr sysrepo.Error
+	if !errors.As(err, &srErr) || srErr.Code != sysrepo.ErrNotFound {
+		t.Errorf("GetItem(%s) = %v, want ErrNotFound", path, err)
+	}
+}
+
+func TestData(t *testing.T) {
+	handler, session := server(t)
+	mtu := "/sysrepo-test:interfaces/interface[name='eth0']/mtu"
+
+	w := request(t, handler, http.MethodPut, eth0, `{"sysrepo-test:interface":[{"name":"eth0","mtu":1500}]}`)
+	expectStatus(t, w, http.StatusCreated)
+	expectItem(t, session, mtu, "1500")
+
+	w = request(t, handler, http.MethodGet, eth0, "")
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), "1500") || w.Header().Get("Content-Type") != "application/yang-data+json" {
+		t.Errorf("GET returned %s: %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	w = request(t, handler, http.MethodPatch, eth0, `{"sysrepo-test:interface":[{"name":"eth0","mtu":9000}]}`)
+	expectStatus(t, w, http.StatusNoContent)
+	expectItem(t, session, mtu, "9000")
+
+	w = request(t, handler, http.MethodPut, eth0, `{"sysrepo-test:interface":[{"name":"eth0"}]}`)
+	expectStatus(t, w, http.StatusNoContent)
+	expectMissing(t, session, mtu)
+
+	w = request(t, handler, http.MethodPost, "/restconf/data/sysrepo-test:interfaces", `{"sysrepo-test:interface":[{"name":"eth1","mtu":1280}]}`)
+	expectStatus(t, w, http.StatusCreated)
+	expectItem(t, session, "/sysrepo-test:interfaces/interface[name='eth1']/mtu", "1280")
+
+	w = request(t, handler, http.MethodPost, "/restconf/data/sysrepo-test:interfaces", `{"sysrepo-test:interface":[{"name":"eth1"}]}`)
+	expectStatus(t, w, http.StatusConflict)
+
+	w = request(t, handler, http.MethodDelete, "/restconf/data/sysrepo-test:interfaces/interface=eth1", "")
+	expectStatus(t, w, http.StatusNoContent)
+	expectMissing(t, session, "/sysrepo-test:interfaces/interface[name='eth1']")
+
+	w = request(t, handler, http.MethodDelete, "/restconf/data/sysrepo-test:interfaces/interface=eth1", "")
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = request(t, handler, http.MethodGet, "/restconf/data/sysrepo-test:interfaces/interface=eth1", "")
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = request(t, handler, http.MethodPatch, "/restconf/data/sysrepo-test:interfaces/interface=eth1", `{"sysrepo-test:interface":[{"name":"eth1"}]}`)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestDatastore(t *testing.T) {
+	handler, session := server(t)
+
+	w := request(t, handler, http.MethodPatch, "/restconf/data", `{"ietf-restconf:data":{"sysrepo-test:system":{"hostname":"r1"}}}`)
+	expectStatus(t, w, http.StatusNoContent)
+	expectItem(t, session, "/sysrepo-test:system/hostname", "r1")
+
+	w = request(t, handler, http.MethodGet, "/restconf/data?content=config", "")
+	expectStatus(t, w, http.StatusOK)
+	if !strings.HasPrefix(w.Body.String(), `{"ietf-restconf:data":`) || !strings.Contains(w.Body.String(), "r1") {
+		t.Errorf("GET of the datastore returned %s", w.Body.String())
+	}
+
+	w = request(t, handler, http.MethodPut, "/restconf/data", `{"ietf-restconf:data":{"sysrepo-test:system":{"hostname":"r2"}}}`)
+	expectStatus(t, w, http.StatusNoContent)
+	expectItem(t, session, "/sysrepo-test:system/hostname", "r2")
+}
+
+func TestBadRequests(t *testing.T) {
+	handler, session := server(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"repeated query parameter", http.MethodGet, "/restconf/data?content=config&content=nonconfig", "", http.StatusBadRequest},
+		{"unsupported query parameter", http.MethodGet, "/restconf/data?depth=1", "", http.StatusBadRequest},
+		{"invalid content", http.MethodGet, "/restconf/data?content=some", "", http.StatusBadRequest},
+		{"unknown module", http.MethodGet, "/restconf/data/unknown:node", "", http.StatusNotFound},
+		{"missing key", http.MethodGet, "/restconf/data/sysrepo-test:interfaces/interface", "", http.StatusBadRequest},
+		{"key with both quotes", http.MethodGet, "/restconf/data/sysrepo-test:interfaces/interface=it's%20%22x%22", "", http.StatusBadRequest},
+		{"PUT key differs from URI", http.MethodPut, eth0, `{"sysrepo-test:interface":[{"name":"eth1","mtu":1500}]}`, http.StatusBadRequest},
+		{"PATCH key differs from URI", http.MethodPatch, eth0, `{"sysrepo-test:interface":[{"name":"eth1","mtu":1500}]}`, http.StatusBadRequest},
+		{"two entries", http.MethodPut, eth0, `{"sysrepo-test:interface":[{"name":"eth0"},{"name":"eth1"}]}`, http.StatusBadRequest},
+		{"unknown resource", http.MethodGet, "/restconf/other", "", http.StatusNotFound},
+		{"operation with GET", http.MethodGet, "/restconf/operations/ietf-factory-default:factory-reset", "", http.StatusMethodNotAllowed},
+		{"unknown operation", http.MethodPost, "/restconf/operations/sysrepo-test:interfaces", "", http.StatusNotFound},
+	}
+
+	// PATCH needs an existing target to reach the key check.
+	w := request(t, handler, http.MethodPut, eth0, `{"sysrepo-test:interface":[{"name":"eth0"}]}`)
+	expectStatus(t, w, http.StatusCreated)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := request(t, handler, test.method, test.path, test.body)
+			expectStatus(t, w, test.status)
+		})
+	}
+
+	expectMissing(t, session, "/sysrepo-test:interfaces/interface[name='eth1']")
+	expectMissing(t, session, "/sysrepo-test:interfaces/interface[name='eth0']/mtu")
+
+	r := httptest.NewRequest(http.MethodGet, "/restconf/data", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	expectStatus(t, w, http.StatusUnauthorized)
+}
+
+func TestOperation(t *testing.T) {
+	handler, session := server(t)
+
+	err := session.SetItem("/sysrepo-test:system/hostname", ptr("r1"), sysrepo.EditDefault)
+	if err == nil {
+		err = session.ApplyChanges(0)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sysrepo implements the factory-reset RPC itself.
+	w := request(t, handler, http.MethodPost, "/restconf/operations/ietf-factory-default:factory-reset", "")
+	expectStatus(t, w, http.StatusNoContent)
+	expectMissing(t, session, "/sysrepo-test:system/hostname")
+}
+
+func TestNacmDenied(t *testing.T) {
+	handler, session := server(t)
+
+	nacm, err := session.NacmInit(sysrepo.SubsDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nacm.Close)
+
+	// Without rules, NACM denies writes and the operations marked
+	// default-deny-all, such as factory-reset.
+	w := request(t, handler, http.MethodPut, eth0, `{"sysrepo-test:interface":[{"name":"eth0"}]}`)
+	expectStatus(t, w, http.StatusForbidden)
+	if !strings.Contains(w.Body.String(), "access-denied") {
+		t.Errorf("denied PUT returned %s", w.Body.String())
+	}
+	expectMissing(t, session, "/sysrepo-test:interfaces/interface[name='eth0']")
+
+	w = request(t, handler, http.MethodPost, "/restconf/operations/ietf-factory-default:factory-reset", "")
+	expectStatus(t, w, http.StatusForbidden)
+
+	w = request(t, handler, http.MethodGet, "/restconf/data/sysrepo-test:interfaces", "")
+	if w.Code == http.StatusForbidden {
+		t.Errorf("NACM denied reading by default: %s", w.Body.String())
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,68 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+import "time"
+
+// RPCSend sends the RPC or action at path, whose list entries must be given
+// with all their keys, e.g. /ietf-system:system-restart or
+// /mod:servers/server[name='a']/reset. The input is the RESTCONF encoding of
+// the "input" node in inputFormat, empty if there is none. The output nodes
+// are returned in outputFormat, empty if there are none.
+func (s *Session) RPCSend(path string, input string, inputFormat DataFormat, outputFormat DataFormat, timeout time.Duration) (string, error) {
+	pathC, freePath := stringToC(path)
+	defer freePath()
+
+	ctx := C.sr_session_acquire_context(s.sess)
+	defer C.sr_session_release_context(s.sess)
+
+	var tree, op *C.struct_lyd_node
+	lyrc := C.lyd_new_path2(nil, ctx, pathC, nil, 0, 0, 0, &tree, &op)
+	if lyrc != C.LY_SUCCESS {
+		return "", Error{
+			Message: "Couldn't create the operation '" + path + "'",
+			Code:    ErrLibyang,
+		}
+	}
+	defer C.lyd_free_all(tree)
+
+	if input != "" {
+		inputC, freeInput := stringToC(input)
+		defer freeInput()
+
+		var in *C.struct_ly_in
+		lyrc = C.ly_in_new_memory(inputC, &in)
+		if lyrc == C.LY_SUCCESS {
+			lyrc = C.lyd_parse_op(ctx, op, in, formatToC(inputFormat), C.LYD_TYPE_RPC_RESTCONF, nil, nil)
+			C.ly_in_free(in, 0)
+		}
+		if lyrc != C.LY_SUCCESS {
+			return "", Error{
+				Message: "Couldn't parse the input of '" + path + "'",
+				Code:    ErrLibyang,
+			}
+		}
+	}
+
+	var output *C.sr_data_t
+	rc := C.sr_rpc_send_tree(s.sess, tree, C.uint32_t(timeout/time.Millisecond), &output)
+	if rc != C.SR_ERR_OK {
+		return "", Error{
+			Message: "Couldn't send '" + path + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+	if output == nil {
+		return "", nil
+	}
+	defer C.sr_release_data(output)
+
+	var outputOp *C.struct_lyd_node
+	lyrc = C.lyd_find_path(output.tree, pathC, 1, &outputOp)
+	if lyrc != C.LY_SUCCESS {
+		return "", nil
+	}
+	return printTree(C.lyd_child(outputOp), outputFormat)
+}
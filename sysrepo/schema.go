@@ -0,0 +1,84 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+
+type SchemaNodeType int
+
+const (
+	SchemaContainer    SchemaNodeType = C.LYS_CONTAINER
+	SchemaLeaf         SchemaNodeType = C.LYS_LEAF
+	SchemaLeafList     SchemaNodeType = C.LYS_LEAFLIST
+	SchemaList         SchemaNodeType = C.LYS_LIST
+	SchemaAnyxml       SchemaNodeType = C.LYS_ANYXML
+	SchemaAnydata      SchemaNodeType = C.LYS_ANYDATA
+	SchemaRPC          SchemaNodeType = C.LYS_RPC
+	SchemaAction       SchemaNodeType = C.LYS_ACTION
+	SchemaNotification SchemaNodeType = C.LYS_NOTIF
+)
+
+// SchemaNode describes a data node of the schema, for clients that address
+// data by other means than the XPath of the Session API.
+type SchemaNode struct {
+	Type SchemaNodeType
+	// Module is the name of the module defining the node and Namespace its
+	// XML namespace.
+	Module    string
+	Namespace string
+	// Config is set for configuration nodes.
+	Config bool
+	// Keys are the names of the keys of a list in their defined order.
+	Keys []string
+}
+
+// FindSchemaNode returns the schema node at path, a data path without
+// predicates such as /ietf-interfaces:interfaces/interface/name.
+func (c *Connection) FindSchemaNode(path string) (*SchemaNode, error) {
+	pathC, free := stringToC(path)
+	defer free()
+
+	ctx := C.sr_acquire_context(c.conn)
+	defer C.sr_release_context(c.conn)
+
+	snode := C.lys_find_path(ctx, nil, pathC, 0)
+	if snode == nil {
+		return nil, Error{
+			Message: "Couldn't find the schema node of '" + path + "'",
+			Code:    ErrNotFound,
+		}
+	}
+
+	result := &SchemaNode{
+		Type:      SchemaNodeType(snode.nodetype),
+		Module:    C.GoString(snode.module.name),
+		Namespace: C.GoString(snode.module.ns),
+		Config:    snode.flags&C.LYS_CONFIG_W != 0,
+	}
+	if snode.nodetype == C.LYS_LIST {
+		for child := C.lysc_node_child(snode); child != nil && child.flags&C.LYS_KEY != 0; child = child.next {
+			result.Keys = append(result.Keys, C.GoString(child.name))
+		}
+	}
+	return result, nil
+}
+
+// ModuleByNamespace returns the name of the implemented module with the XML
+// namespace.
+func (c *Connection) ModuleByNamespace(namespace string) (string, error) {
+	ctx := C.sr_acquire_context(c.conn)
+	defer C.sr_release_context(c.conn)
+
+	var index C.uint32_t
+	for mod := C.ly_ctx_get_module_iter(ctx, &index); mod != nil; mod = C.ly_ctx_get_module_iter(ctx, &index) {
+		if mod.implemented != 0 && C.GoString(mod.ns) == namespace {
+			return C.GoString(mod.name), nil
+		}
+	}
+
+	return "", Error{
+		Message: "No module with the namespace '" + namespace + "'",
+		Code:    ErrNotFound,
+	}
+}
@@ -84,11 +84,17 @@ package sysrepo
     return mem;
 }
 
+   // Non-variadic wrapper, cgo can't call variadic functions
+   static int sr_session_set_error_message_str(sr_session_ctx_t *session, const char *message) {
+    return sr_session_set_error_message(session, "%s", message);
+}
+
 */
 import "C"
 import (
 	"errors"
 	"github.com/mattiaswal/go-libyang/libyang"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -97,6 +103,8 @@ type Session struct {
 	sess         *C.sr_session_ctx_t
 	conn         *Connection // Keep reference to connection to prevent GC
 	cleanupTasks []func()
+	txOnce       sync.Once
+	txRunning    chan struct{} // Holds a value while a transaction runs
 }
 
 // Close stops the session
@@ -236,6 +244,12 @@ func (s *Session) DiscardChanges(xpath *string) error {
 	return throwIfError(rc, "Couldn't discard changes")
 }
 
+// HasChanges reports whether the session has prepared edits which are not
+// applied yet.
+func (s *Session) HasChanges() bool {
+	return C.sr_has_changes(s.sess) != 0
+}
+
 func (s *Session) CopyConfig(source Datastore, moduleName *string, timeout time.Duration) error {
 	var moduleNameC *C.char
 	var free func()
@@ -248,11 +262,269 @@ func (s *Session) CopyConfig(source Datastore, moduleName *string, timeout time.
 	return throwIfError(rc, "Couldn't copy config")
 }
 
+// ExportData returns the data selected by xpath printed in the given format.
+// An empty string is returned if there is no such data.
+func (s *Session) ExportData(xpath string, format DataFormat, opts GetOptions, timeout time.Duration) (string, error) {
+	xpathC, freeXpath := stringToC(xpath)
+	defer freeXpath()
+
+	var data *C.sr_data_t
+	rc := C.sr_get_data(s.sess, xpathC, 0, C.uint(timeout/time.Millisecond), C.uint(opts), &data)
+	if rc != C.SR_ERR_OK {
+		return "", Error{
+			Message: "Couldn't get '" + xpath + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+	if data == nil {
+		return "", nil
+	}
+	defer C.sr_release_data(data)
+
+	return printTree(data.tree, format)
+}
+
+// ExportNode returns the node at path with its subtree, without its parents,
+// printed in the given format. An empty string is returned if there is no such
+// node.
+func (s *Session) ExportNode(path string, format DataFormat, opts GetOptions, timeout time.Duration) (string, error) {
+	pathC, freePath := stringToC(path)
+	defer freePath()
+
+	var data *C.sr_data_t
+	rc := C.sr_get_data(s.sess, pathC, 0, C.uint(timeout/time.Millisecond), C.uint(opts), &data)
+	if rc != C.SR_ERR_OK {
+		return "", Error{
+			Message: "Couldn't get '" + path + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+	if data == nil {
+		return "", nil
+	}
+	defer C.sr_release_data(data)
+
+	var node *C.struct_lyd_node
+	if C.lyd_find_path(data.tree, pathC, 0, &node) != C.LY_SUCCESS {
+		return "", nil
+	}
+
+	var out *C.char
+	lyrc := C.lyd_print_mem(&out, node, formatToC(format), 0)
+	if lyrc != C.LY_SUCCESS {
+		return "", Error{
+			Message: "Couldn't print '" + path + "'",
+			Code:    ErrLibyang,
+		}
+	}
+	defer C.free(unsafe.Pointer(out))
+
+	return C.GoString(out), nil
+}
+
+// ReplaceConfig replaces the configuration of the active datastore with data
+// in the given format. A nil moduleName replaces all modules and an empty
+// data removes the configuration.
+func (s *Session) ReplaceConfig(moduleName *string, data string, format DataFormat, timeout time.Duration) error {
+	var moduleNameC *C.char
+	var free func()
+	if moduleName != nil {
+		moduleNameC, free = stringToC(*moduleName)
+		defer free()
+	}
+
+	ctx := C.sr_session_acquire_context(s.sess)
+	defer C.sr_session_release_context(s.sess)
+
+	tree, err := parseTree(ctx, data, format, parseConfig)
+	if err != nil {
+		return err
+	}
+
+	rc := C.sr_replace_config(s.sess, moduleNameC, tree, C.uint(timeout/time.Millisecond))
+	return throwIfError(rc, "Couldn't replace config")
+}
+
+// EditBatch prepares the edit in data, a NETCONF edit-config content in the
+// given format whose nodes may carry ietf-netconf operation attributes, like
+// SetItem and DeleteItem do. It is applied with ApplyChanges.
+func (s *Session) EditBatch(data string, format DataFormat, defaultOperation DefaultOperation) error {
+	operationC, freeOperation := stringToC(string(defaultOperation))
+	defer freeOperation()
+
+	ctx := C.sr_session_acquire_context(s.sess)
+	defer C.sr_session_release_context(s.sess)
+
+	tree, err := parseTree(ctx, data, format, parseEdit)
+	if err != nil {
+		return err
+	}
+	if tree == nil {
+		return nil
+	}
+	defer C.lyd_free_all(tree)
+
+	rc := C.sr_edit_batch(s.sess, tree, operationC)
+	return throwIfError(rc, "Couldn't prepare the edit")
+}
+
+// EditSubtree prepares the edit of the nodes in data, given in the format as
+// children of the node at parentPath, or as top-level nodes if it is empty.
+// The nodes get the ietf-netconf operation, such as "create" or "replace",
+// while parentPath and the nodes without an operation are merged. If target
+// is set, data must hold exactly the node at that path, with the same keys.
+func (s *Session) EditSubtree(parentPath string, data string, format DataFormat, operation string, target *string) error {
+	ctx := C.sr_session_acquire_context(s.sess)
+	defer C.sr_session_release_context(s.sess)
+
+	var tree, parent *C.struct_lyd_node
+	defer func() { C.lyd_free_all(tree) }()
+
+	if parentPath != "" {
+		pathC, freePath := stringToC(parentPath)
+		defer freePath()
+
+		lyrc := C.lyd_new_path2(nil, ctx, pathC, nil, 0, 0, 0, &tree, &parent)
+		if lyrc != C.LY_SUCCESS {
+			return Error{
+				Message: "Couldn't create '" + parentPath + "'",
+				Code:    ErrLibyang,
+			}
+		}
+	}
+
+	// The parsed nodes are appended after the keys of a list entry
+	var last *C.struct_lyd_node
+	for child := C.lyd_child(parent); child != nil; child = child.next {
+		last = child
+	}
+
+	dataC, freeData := stringToC(data)
+	defer freeData()
+
+	var in *C.struct_ly_in
+	var parsed *C.struct_lyd_node
+	lyrc := C.ly_in_new_memory(dataC, &in)
+	if lyrc == C.LY_SUCCESS {
+		lyrc = C.lyd_parse_data(ctx, parent, in, formatToC(format), parseConfig, 0, &parsed)
+		C.ly_in_free(in, 0)
+	}
+	if parent == nil {
+		tree = parsed
+	}
+	if lyrc != C.LY_SUCCESS {
+		return Error{
+			Message: "Couldn't parse data",
+			Code:    ErrLibyang,
+		}
+	}
+
+	first := parsed
+	if parent != nil {
+		first = C.lyd_child(parent)
+		if last != nil {
+			first = last.next
+		}
+	}
+
+	if target != nil {
+		targetC, freeTarget := stringToC(*target)
+		defer freeTarget()
+
+		var match *C.struct_lyd_node
+		if first == nil || first.next != nil || C.lyd_find_path(first, targetC, 0, &match) != C.LY_SUCCESS || match != first {
+			return Error{
+				Message: "The data is not the node '" + *target + "'",
+				Code:    ErrInvalArg,
+			}
+		}
+	}
+	if first == nil {
+		return nil
+	}
+
+	if operation != "" {
+		nameC, freeName := stringToC("ietf-netconf:operation")
+		defer freeName()
+
+		operationC, freeOperation := stringToC(operation)
+		defer freeOperation()
+
+		for node := first; node != nil; node = node.next {
+			if C.lyd_new_meta(ctx, node, nil, nameC, operationC, 0, nil) != C.LY_SUCCESS {
+				return Error{
+					Message: "Couldn't set the operation '" + operation + "'",
+					Code:    ErrLibyang,
+				}
+			}
+		}
+	}
+
+	mergeC, freeMerge := stringToC(string(OpMerge))
+	defer freeMerge()
+
+	rc := C.sr_edit_batch(s.sess, tree, mergeC)
+	return throwIfError(rc, "Couldn't prepare the edit")
+}
+
+// FactoryReset replaces the startup and running datastores with the content of
+// the factory-default datastore. A nil moduleName resets all modules.
+func (s *Session) FactoryReset(moduleName *string, timeout time.Duration) (err error) {
+	currentDs := s.ActiveDatastore()
+	defer func() {
+		err = errors.Join(err, s.SwitchDatastore(currentDs))
+	}()
+
+	for _, ds := range []Datastore{DSStartup, DSRunning} {
+		err = s.SwitchDatastore(ds)
+		if err != nil {
+			return err
+		}
+
+		err = s.CopyConfig(DSFactoryDefault, moduleName, timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 type ErrorInfo struct {
 	Code    ErrorCode
 	Message string
 }
 
+// SetErrorMessage sets the error message reported to the originator of the
+// event being handled by a callback.
+func (s *Session) SetErrorMessage(message string) error {
+	messageC := C.CString(message)
+	defer C.free(unsafe.Pointer(messageC))
+
+	rc := C.sr_session_set_error_message_str(s.sess, messageC)
+	return throwIfError(rc, "Couldn't set error message")
+}
+
+// GetErrors returns the errors reported by the last failed operation of the
+// session.
+func (s *Session) GetErrors() []ErrorInfo {
+	var info *C.sr_error_info_t
+	rc := C.sr_session_get_error(s.sess, &info)
+	if rc != C.SR_ERR_OK || info == nil {
+		return nil
+	}
+
+	errs := unsafe.Slice(info.err, info.err_count)
+	result := make([]ErrorInfo, 0, len(errs))
+	for _, e := range errs {
+		result = append(result, ErrorInfo{
+			Code:    ErrorCode(e.err_code),
+			Message: C.GoString(e.message),
+		})
+	}
+	return result
+}
+
 func (s *Session) GetOriginatorName() string {
 	return C.GoString(C.sr_session_get_orig_name(s.sess))
 }
@@ -265,6 +537,42 @@ func (s *Session) SetOriginatorName(originatorName string) error {
 	return throwIfError(rc, "Couldn't set originator name")
 }
 
+// PushOriginatorData appends a chunk of data to the originator data of the
+// session, sent along with its edits, RPCs and notifications. The meaning of
+// the chunks is defined by the originator name.
+func (s *Session) PushOriginatorData(data []byte) error {
+	var dataC unsafe.Pointer
+	if len(data) > 0 {
+		dataC = C.CBytes(data)
+		defer C.free(dataC)
+	}
+
+	rc := C.sr_session_push_orig_data(s.sess, C.uint32_t(len(data)), dataC)
+	return throwIfError(rc, "Couldn't push originator data")
+}
+
+// ClearOriginatorData removes all originator data of the session.
+func (s *Session) ClearOriginatorData() {
+	C.sr_session_del_orig_data(s.sess)
+}
+
+// OriginatorData returns the chunk of originator data at index. In callbacks
+// it is the data of the originator of the event.
+func (s *Session) OriginatorData(index uint32) ([]byte, error) {
+	var size C.uint32_t
+	var data unsafe.Pointer
+
+	rc := C.sr_session_get_orig_data(s.sess, C.uint32_t(index), &size, &data)
+	if rc != C.SR_ERR_OK {
+		return nil, Error{
+			Message: "Couldn't get originator data",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	return C.GoBytes(data, C.int(size)), nil
+}
+
 func (s *Session) GetConnection() *Connection {
 	return s.conn
 }
@@ -273,6 +581,23 @@ func (s *Session) GetId() uint32 {
 	return uint32(C.sr_session_get_id(s.sess))
 }
 
+// SetUser makes the session act on behalf of the system user, checking module
+// file permissions against that user. Only a privileged process may do it.
+func (s *Session) SetUser(user string) error {
+	userC, free := stringToC(user)
+	defer free()
+
+	rc := C.sr_session_set_user(s.sess, userC)
+	return throwIfError(rc, "Couldn't set session user '"+user+"'")
+}
+
+// User returns the system user the session acts on behalf of.
+func (s *Session) User() string {
+	return C.GoString(C.sr_session_get_user(s.sess))
+}
+
+// SetNacmUser sets the user whose NACM rules apply to the session. They are
+// only enforced while NACM is initialized in the process, see NacmInit.
 func (s *Session) SetNacmUser(user string) error {
 	userC, free := stringToC(user)
 	defer free()
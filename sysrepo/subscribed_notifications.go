@@ -0,0 +1,335 @@
+package sysrepo
+
+/*
+ #cgo LDFLAGS: -lsysrepo
+ #include <stdlib.h>
+ #include <poll.h>
+ #include <unistd.h>
+ #include <sysrepo.h>
+ #include <sysrepo/subscribed_notifications.h>
+*/
+import "C"
+import (
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/mattiaswal/go-libyang/libyang"
+)
+
+// notifPollInterval is how often a stream reader checks whether it was
+// terminated while waiting for notifications.
+const notifPollInterval = 100 * time.Millisecond
+
+// Notification is a received notification. The tree is owned by the receiver,
+// which must free it.
+type Notification struct {
+	Tree      libyang.DataNode
+	Timestamp time.Time
+}
+
+// YangPushChange is a kind of change which can be excluded from on-change
+// YANG push updates.
+type YangPushChange int
+
+const (
+	YangPushCreate  YangPushChange = C.SRSN_YP_CHANGE_CREATE
+	YangPushDelete  YangPushChange = C.SRSN_YP_CHANGE_DELETE
+	YangPushInsert  YangPushChange = C.SRSN_YP_CHANGE_INSERT
+	YangPushMove    YangPushChange = C.SRSN_YP_CHANGE_MOVE
+	YangPushReplace YangPushChange = C.SRSN_YP_CHANGE_REPLACE
+)
+
+// NotificationStream is a subscribed notifications (RFC 8639) or YANG push
+// (RFC 8641) subscription. Its notifications, including the
+// ietf-subscribed-notifications state change notifications, are delivered to
+// the channel returned by Notifications, which is closed when the
+// subscription ends, either by itself or by Terminate or Close.
+type NotificationStream struct {
+	// ID is the subscription ID used in the notifications.
+	ID uint32
+	// ReplayStart is the time of the first replayed notification, if any.
+	ReplayStart *time.Time
+
+	session    *Session // Keep reference to session to prevent GC
+	sub        *C.sr_subscription_ctx_t
+	fd         C.int
+	notifs     chan Notification
+	stop       chan struct{}
+	done       chan struct{}
+	once       sync.Once
+	terminated bool // Set by Terminate before stopping the reader
+}
+
+// SubscribeNotifications subscribes to the notifications of stream, e.g.
+// "NETCONF", optionally filtered by xpathFilter. If start is set, stored
+// notifications since then are replayed first. If stop is set, the
+// subscription ends then.
+func (s *Session) SubscribeNotifications(stream string, xpathFilter *string, start *time.Time, stop *time.Time) (*NotificationStream, error) {
+	streamC, freeStream := stringToC(stream)
+	defer freeStream()
+
+	var xpathC *C.char
+	var free func()
+	if xpathFilter != nil {
+		xpathC, free = stringToC(*xpathFilter)
+		defer free()
+	}
+
+	var sub *C.sr_subscription_ctx_t
+	var replayStart C.struct_timespec
+	var fd C.int
+	var id C.uint32_t
+
+	rc := C.srsn_subscribe(s.sess, streamC, xpathC, timeToC(stop), timeToC(start), 0, &sub, &replayStart, &fd, &id)
+	if rc != C.SR_ERR_OK {
+		return nil, Error{
+			Message: "Couldn't subscribe to stream '" + stream + "'",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	n := newNotificationStream(s, sub, fd, id)
+	if replayStart.tv_sec != 0 || replayStart.tv_nsec != 0 {
+		t := timeFromC(replayStart)
+		n.ReplayStart = &t
+	}
+	return n, nil
+}
+
+// YangPushPeriodic subscribes to periodic updates of the data of ds selected
+// by xpathFilter. Updates are sent every period, aligned to anchor if set.
+func (s *Session) YangPushPeriodic(ds Datastore, xpathFilter *string, period time.Duration, anchor *time.Time, stop *time.Time) (*NotificationStream, error) {
+	var xpathC *C.char
+	var free func()
+	if xpathFilter != nil {
+		xpathC, free = stringToC(*xpathFilter)
+		defer free()
+	}
+
+	var fd C.int
+	var id C.uint32_t
+
+	rc := C.srsn_yang_push_periodic(s.sess, C.sr_datastore_t(ds), xpathC, C.uint32_t(period/time.Millisecond), timeToC(anchor), timeToC(stop), &fd, &id)
+	if rc != C.SR_ERR_OK {
+		return nil, Error{
+			Message: "Couldn't subscribe to periodic YANG push",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	return newNotificationStream(s, nil, fd, id), nil
+}
+
+// YangPushOnChange subscribes to updates of the data of ds selected by
+// xpathFilter whenever it changes, at most once per dampening period. With
+// syncOnStart, the full data is sent first.
+func (s *Session) YangPushOnChange(ds Datastore, xpathFilter *string, dampening time.Duration, syncOnStart bool, excluded []YangPushChange, stop *time.Time) (*NotificationStream, error) {
+	var xpathC *C.char
+	var free func()
+	if xpathFilter != nil {
+		xpathC, free = stringToC(*xpathFilter)
+		defer free()
+	}
+
+	var excludedC [C.SRSN_YP_CHANGE_COUNT]C.int
+	for _, change := range excluded {
+		if change < 0 || change >= C.SRSN_YP_CHANGE_COUNT {
+			return nil, Error{
+				Message: "Invalid YANG push change",
+				Code:    ErrInvalArg,
+			}
+		}
+		excludedC[change] = 1
+	}
+
+	var syncOnStartC C.int
+	if syncOnStart {
+		syncOnStartC = 1
+	}
+
+	var sub *C.sr_subscription_ctx_t
+	var fd C.int
+	var id C.uint32_t
+
+	rc := C.srsn_yang_push_on_change(s.sess, C.sr_datastore_t(ds), xpathC, C.uint32_t(dampening/time.Millisecond), syncOnStartC, &excludedC[0], timeToC(stop), 0, &sub, &fd, &id)
+	if rc != C.SR_ERR_OK {
+		return nil, Error{
+			Message: "Couldn't subscribe to on-change YANG push",
+			Code:    ErrorCode(rc),
+		}
+	}
+
+	return newNotificationStream(s, sub, fd, id), nil
+}
+
+func newNotificationStream(session *Session, sub *C.sr_subscription_ctx_t, fd C.int, id C.uint32_t) *NotificationStream {
+	n := &NotificationStream{
+		ID:      uint32(id),
+		session: session,
+		sub:     sub,
+		fd:      fd,
+		notifs:  make(chan Notification),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go n.read()
+	return n
+}
+
+// Notifications returns the channel the notifications are delivered to.
+func (n *NotificationStream) Notifications() <-chan Notification {
+	return n.notifs
+}
+
+// ModifyFilter changes the xpath filter of the subscription. A nil
+// xpathFilter removes it.
+func (n *NotificationStream) ModifyFilter(xpathFilter *string) error {
+	var xpathC *C.char
+	var free func()
+	if xpathFilter != nil {
+		xpathC, free = stringToC(*xpathFilter)
+		defer free()
+	}
+
+	rc := C.srsn_modify_xpath_filter(C.uint32_t(n.ID), xpathC)
+	return throwIfError(rc, "Couldn't modify subscription filter")
+}
+
+// ModifyStopTime changes the stop time of the subscription. A nil stop makes
+// it run until terminated.
+func (n *NotificationStream) ModifyStopTime(stop *time.Time) error {
+	rc := C.srsn_modify_stop_time(C.uint32_t(n.ID), timeToC(stop))
+	return throwIfError(rc, "Couldn't modify subscription stop time")
+}
+
+// ModifyPeriod changes the period and anchor of a periodic YANG push
+// subscription.
+func (n *NotificationStream) ModifyPeriod(period time.Duration, anchor *time.Time) error {
+	rc := C.srsn_yang_push_modify_periodic(C.uint32_t(n.ID), C.uint32_t(period/time.Millisecond), timeToC(anchor))
+	return throwIfError(rc, "Couldn't modify subscription period")
+}
+
+// ModifyDampening changes the dampening period of an on-change YANG push
+// subscription.
+func (n *NotificationStream) ModifyDampening(dampening time.Duration) error {
+	rc := C.srsn_yang_push_modify_on_change(C.uint32_t(n.ID), C.uint32_t(dampening/time.Millisecond))
+	return throwIfError(rc, "Couldn't modify subscription dampening period")
+}
+
+// Suspend stops sending notifications until Resume is called. Reason is an
+// identity of ietf-subscribed-notifications, e.g.
+// "ietf-subscribed-notifications:insufficient-resources".
+func (n *NotificationStream) Suspend(reason string) error {
+	reasonC, free := stringToC(reason)
+	defer free()
+
+	rc := C.srsn_suspend(C.uint32_t(n.ID), reasonC)
+	return throwIfError(rc, "Couldn't suspend subscription")
+}
+
+func (n *NotificationStream) Resume() error {
+	rc := C.srsn_resume(C.uint32_t(n.ID))
+	return throwIfError(rc, "Couldn't resume subscription")
+}
+
+// Terminate ends the subscription with a reason identity, e.g.
+// "ietf-subscribed-notifications:no-such-subscription", and closes the
+// notification channel.
+func (n *NotificationStream) Terminate(reason string) error {
+	reasonC, free := stringToC(reason)
+	defer free()
+
+	rc := C.srsn_terminate(C.uint32_t(n.ID), reasonC)
+	if rc == C.SR_ERR_OK {
+		n.terminated = true
+	}
+	n.Close()
+	return throwIfError(rc, "Couldn't terminate subscription")
+}
+
+// Close stops reading the subscription and releases it without sending a
+// subscription-terminated notification, and closes the notification channel.
+// Streams which ended by themselves are released automatically.
+func (n *NotificationStream) Close() {
+	n.once.Do(func() {
+		close(n.stop)
+	})
+	<-n.done
+}
+
+func (n *NotificationStream) read() {
+	defer close(n.done)
+	defer func() {
+		if n.sub != nil {
+			C.sr_unsubscribe(n.sub)
+			n.sub = nil
+		}
+	}()
+	defer close(n.notifs)
+	defer C.close(n.fd)
+
+	// A stream stopped by Close is still registered, and periodic streams
+	// would keep writing into the closed pipe.
+	stopped := false
+	defer func() {
+		if stopped && !n.terminated {
+			C.srsn_terminate(C.uint32_t(n.ID), nil)
+		}
+	}()
+
+	pfd := C.struct_pollfd{
+		fd:     n.fd,
+		events: C.POLLIN,
+	}
+
+	for {
+		select {
+		case <-n.stop:
+			stopped = true
+			return
+		default:
+		}
+
+		pfd.revents = 0
+		ready := C.poll(&pfd, 1, C.int(notifPollInterval/time.Millisecond))
+		if ready == 0 {
+			continue
+		}
+		if ready < 0 || pfd.revents&C.POLLIN == 0 {
+			return
+		}
+
+		notif, ok := n.readNotif()
+		if !ok {
+			return
+		}
+
+		select {
+		case n.notifs <- notif:
+		case <-n.stop:
+			notif.Tree.Free()
+			stopped = true
+			return
+		}
+	}
+}
+
+func (n *NotificationStream) readNotif() (Notification, bool) {
+	conn := n.session.conn.conn
+	ctx := C.sr_acquire_context(conn)
+	defer C.sr_release_context(conn)
+
+	var timestamp C.struct_timespec
+	var tree *C.struct_lyd_node
+	rc := C.srsn_read_notif(n.fd, ctx, &timestamp, &tree)
+	if rc != C.SR_ERR_OK {
+		return Notification{}, false
+	}
+
+	return Notification{
+		Tree:      libyang.NewNode(unsafe.Pointer(tree)),
+		Timestamp: timeFromC(timestamp),
+	}, true
+}
@@ -0,0 +1,40 @@
+package sysrepo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepotest"
+)
+
+func TestNotificationStreamClosePeriodic(t *testing.T) {
+	conn := sysrepotest.New(t, "testdata/sysrepo-test.yang")
+	session := sysrepotest.Session(t, conn, sysrepo.DSRunning)
+
+	stream, err := session.YangPushPeriodic(sysrepo.DSRunning, nil, 10*time.Millisecond, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case notif, ok := <-stream.Notifications():
+		if !ok {
+			t.Fatal("stream ended before its first update")
+		}
+		notif.Tree.Free()
+	case <-time.After(5 * time.Second):
+		t.Fatal("no periodic update received")
+	}
+
+	stream.Close()
+	if _, ok := <-stream.Notifications(); ok {
+		t.Error("notification received after Close")
+	}
+
+	// A subscription still registered in sysrepo could be modified.
+	err = stream.ModifyPeriod(time.Second, nil)
+	if err == nil {
+		t.Error("ModifyPeriod() after Close succeeded")
+	}
+}
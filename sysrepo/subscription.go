@@ -0,0 +1,68 @@
+package sysrepo
+
+// #cgo LDFLAGS: -lsysrepo
+// #include <stdlib.h>
+// #include <sysrepo.h>
+import "C"
+import (
+	"runtime"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// Subscription is a set of sysrepo subscriptions which is kept active until
+// it is closed.
+type Subscription struct {
+	sub          *C.sr_subscription_ctx_t
+	session      *Session // Keep reference to session to prevent GC
+	cleanupTasks []func()
+}
+
+func newSubscription(session *Session, sub *C.sr_subscription_ctx_t) *Subscription {
+	subscription := &Subscription{
+		sub:     sub,
+		session: session,
+	}
+	runtime.SetFinalizer(subscription, (*Subscription).Close)
+	return subscription
+}
+
+// Close removes all the subscriptions.
+func (s *Subscription) Close() {
+	if s.sub != nil {
+		C.sr_unsubscribe(s.sub)
+		s.sub = nil
+	}
+
+	for i := len(s.cleanupTasks) - 1; i >= 0; i-- {
+		s.cleanupTasks[i]()
+	}
+	s.cleanupTasks = nil
+}
+
+// newCallbackData stores v in C memory so that it can be passed to sysrepo as
+// the private data of a callback. The returned function releases it.
+func newCallbackData(v any) (unsafe.Pointer, func()) {
+	handle := cgo.NewHandle(v)
+	data := C.malloc(C.sizeof_uintptr_t)
+	*(*C.uintptr_t)(data) = C.uintptr_t(handle)
+
+	return data, func() {
+		handle.Delete()
+		C.free(data)
+	}
+}
+
+// callbackData returns the value stored by newCallbackData.
+func callbackData(data unsafe.Pointer) any {
+	return cgo.Handle(*(*C.uintptr_t)(data)).Value()
+}
+
+// eventSession wraps the implicit session sysrepo passes to callbacks. It is
+// owned by sysrepo and only valid during the callback.
+func eventSession(sess *C.sr_session_ctx_t, owner *Session) *Session {
+	return &Session{
+		sess: sess,
+		conn: owner.conn,
+	}
+}
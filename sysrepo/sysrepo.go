@@ -9,7 +9,10 @@ package sysrepo
 import "C"
 import (
 	"fmt"
+	"time"
 	"unsafe"
+
+	"github.com/mattiaswal/go-libyang/libyang"
 )
 
 type ErrorCode int
@@ -61,6 +64,76 @@ func stringToC(s string) (*C.char, func()) {
 	return cstr, func() { C.free(unsafe.Pointer(cstr)) }
 }
 
+// timeToC converts an optional time to a timespec, nil if unset.
+func timeToC(t *time.Time) *C.struct_timespec {
+	if t == nil {
+		return nil
+	}
+	return &C.struct_timespec{
+		tv_sec:  C.time_t(t.Unix()),
+		tv_nsec: C.long(t.Nanosecond()),
+	}
+}
+
+func timeFromC(ts C.struct_timespec) time.Time {
+	return time.Unix(int64(ts.tv_sec), int64(ts.tv_nsec))
+}
+
+// nodeToC returns the libyang node wrapped by a DataNode.
+func nodeToC(node libyang.DataNode) *C.struct_lyd_node {
+	return (*C.struct_lyd_node)(unsafe.Pointer(node.Ptr))
+}
+
+// printTree prints a data tree and all its siblings in the given format.
+func printTree(tree *C.struct_lyd_node, format DataFormat) (string, error) {
+	if tree == nil {
+		return "", nil
+	}
+
+	var out *C.char
+	lyrc := C.lyd_print_mem(&out, tree, formatToC(format), C.LYD_PRINT_WITHSIBLINGS)
+	if lyrc != C.LY_SUCCESS {
+		return "", Error{
+			Message: "Couldn't print data",
+			Code:    ErrLibyang,
+		}
+	}
+	defer C.free(unsafe.Pointer(out))
+
+	return C.GoString(out), nil
+}
+
+const (
+	// parseConfig are the libyang options for parsing configuration data.
+	parseConfig = C.LYD_PARSE_ONLY | C.LYD_PARSE_STRICT | C.LYD_PARSE_NO_STATE
+	// parseEdit are the options for parsing edit content, as used by
+	// sysrepocfg. Opaque nodes allow valueless nodes to delete or remove,
+	// such as <mtu nc:operation="delete"/>.
+	parseEdit = C.LYD_PARSE_ONLY | C.LYD_PARSE_OPAQ | C.LYD_PARSE_NO_STATE
+)
+
+// parseTree parses data in the given format with the parse options. The
+// caller owns the returned tree, which is nil for empty data.
+func parseTree(ctx *C.struct_ly_ctx, data string, format DataFormat, options C.uint32_t) (*C.struct_lyd_node, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	dataC, free := stringToC(data)
+	defer free()
+
+	var tree *C.struct_lyd_node
+	lyrc := C.lyd_parse_data_mem(ctx, dataC, formatToC(format), options, 0, &tree)
+	if lyrc != C.LY_SUCCESS {
+		return nil, Error{
+			Message: "Couldn't parse data",
+			Code:    ErrLibyang,
+		}
+	}
+
+	return tree, nil
+}
+
 type Datastore int
 
 const (
@@ -71,6 +144,27 @@ const (
 	DSFactoryDefault Datastore = C.SR_DS_FACTORY_DEFAULT
 )
 
+// DataFormat is the libyang data format, so trees and serialized data use the
+// same constants.
+type DataFormat = libyang.DataFormat
+
+const (
+	FormatXML  = libyang.DataFormatXML
+	FormatJSON = libyang.DataFormatJSON
+)
+
+// formatToC returns the libyang format of a DataFormat, LYD_UNKNOWN, which
+// libyang rejects, for the others.
+func formatToC(format DataFormat) C.LYD_FORMAT {
+	switch format {
+	case FormatXML:
+		return C.LYD_XML
+	case FormatJSON:
+		return C.LYD_JSON
+	}
+	return C.LYD_UNKNOWN
+}
+
 type Event int
 
 const (
@@ -131,6 +225,7 @@ const (
 	SubsUpdate        SubscribeOptions = C.SR_SUBSCR_UPDATE
 	SubsOperMerge     SubscribeOptions = C.SR_SUBSCR_OPER_MERGE
 	SubsThreadSuspend SubscribeOptions = C.SR_SUBSCR_THREAD_SUSPEND
+	SubsOperPollDiff  SubscribeOptions = C.SR_SUBSCR_OPER_POLL_DIFF
 )
 
 type EditOptions int
@@ -0,0 +1,135 @@
+// Package sysrepoapi describes the basic operations of sysrepo connections and
+// sessions as interfaces without depending on cgo. Code written against it can
+// run on the real datastore through Connection.API of package sysrepo, and be
+// unit tested against the in-memory implementation of package sysrepofake.
+package sysrepoapi
+
+import (
+	"fmt"
+	"time"
+)
+
+type ErrorCode int
+
+const (
+	ErrOk ErrorCode = iota
+	ErrInvalArg
+	ErrLibyang
+	ErrSyscallFailed
+	ErrNoMemory
+	ErrNotFound
+	ErrExists
+	ErrInternal
+	ErrUnsupported
+	ErrValidationFailed
+	ErrOperationFailed
+	ErrUnauthorized
+	ErrLocked
+	ErrTimeout
+	ErrCallbackFailed
+	ErrCallbackShelve
+)
+
+type Error struct {
+	Message string
+	Code    ErrorCode
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s (code: %d)", e.Message, e.Code)
+}
+
+type Datastore int
+
+const (
+	DSRunning Datastore = iota
+	DSCandidate
+	DSStartup
+	DSOperational
+	DSFactoryDefault
+)
+
+type Event int
+
+const (
+	EvChange Event = iota
+	EvDone
+	EvAbort
+	EvEnabled
+	EvUpdate
+)
+
+type ChangeOperation int
+
+const (
+	OpCreated ChangeOperation = iota
+	OpModified
+	OpDeleted
+	OpMoved
+)
+
+type MovePosition int
+
+const (
+	MoveBefore MovePosition = iota
+	MoveAfter
+	MoveFirst
+	MoveLast
+)
+
+type SubscribeOptions int
+
+const (
+	SubsDefault  SubscribeOptions = 0
+	SubsDoneOnly SubscribeOptions = 1 << iota
+	SubsEnabled
+	SubsUpdate
+)
+
+type EditOptions int
+
+const (
+	EditDefault      EditOptions = 0
+	EditNonRecursive EditOptions = 1 << iota
+	EditStrict
+	EditIsolate
+)
+
+// Change is a single change of a node reported to module change callbacks.
+type Change struct {
+	Operation ChangeOperation
+	// Path and Value describe the changed node. Value is nil for nodes
+	// without a value.
+	Path            string
+	Value           *string
+	PreviousValue   *string
+	PreviousList    *string
+	PreviousDefault bool
+}
+
+// ModuleChangeCallback is called for the events of a module change
+// subscription with the changes of the module under the subscribed xpath.
+// Returning an error in EvChange or EvUpdate rejects the changes.
+type ModuleChangeCallback func(session Session, moduleName string, xpath *string, event Event, changes []Change) error
+
+type Connection interface {
+	SessionStart(datastore Datastore) (Session, error)
+	Close()
+}
+
+type Session interface {
+	ActiveDatastore() Datastore
+	SwitchDatastore(datastore Datastore) error
+	GetItem(path string) (string, error)
+	SetItem(path string, value *string, opts EditOptions) error
+	DeleteItem(path string, opts EditOptions) error
+	MoveItem(path string, position MovePosition, keysOrValue *string, origin *string, opts EditOptions) error
+	ApplyChanges(timeout time.Duration) error
+	DiscardChanges(xpath *string) error
+	ModuleChangeSubscribe(moduleName string, xpath *string, callback ModuleChangeCallback, priority uint32, opts SubscribeOptions) (Subscription, error)
+	Close()
+}
+
+type Subscription interface {
+	Close()
+}
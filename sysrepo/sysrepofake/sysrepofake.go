@@ -0,0 +1,410 @@
+// Package sysrepofake is an in-memory implementation of the sysrepoapi
+// interfaces for unit tests that cannot use libsysrepo.
+//
+// The datastores are schema-less trees: any path can be set, list entries are
+// identified by the key predicates of their paths, e.g.
+// /ietf-interfaces:interfaces/interface[name='eth0'], and leaf-list entries
+// must be addressed by their value, e.g. /mod:servers/server[.='ntp1']. No
+// validation, defaults or NACM take place, and the operational datastore only
+// holds the data set in it.
+//
+// ApplyChanges calls the module change callbacks synchronously, before it
+// returns, in the same order of events as sysrepo. A callback must therefore
+// not apply changes of its own.
+package sysrepofake
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepoapi"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/xpath"
+)
+
+var (
+	_ sysrepoapi.Connection   = (*Connection)(nil)
+	_ sysrepoapi.Session      = (*Session)(nil)
+	_ sysrepoapi.Subscription = (*Subscription)(nil)
+)
+
+// Connection holds the datastores and the subscriptions shared by its
+// sessions.
+type Connection struct {
+	applyMu       sync.Mutex // Serializes applying changes and subscribing
+	mu            sync.Mutex
+	stores        map[sysrepoapi.Datastore]*node // Replaced, never modified
+	subscriptions []*Subscription
+}
+
+// New returns a connection with empty datastores.
+func New() *Connection {
+	return &Connection{
+		stores: map[sysrepoapi.Datastore]*node{},
+	}
+}
+
+func (c *Connection) SessionStart(datastore sysrepoapi.Datastore) (sysrepoapi.Session, error) {
+	err := checkDatastore(datastore)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{conn: c, ds: datastore}, nil
+}
+
+// Close removes all subscriptions. The data is kept.
+func (c *Connection) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscriptions = nil
+}
+
+func (c *Connection) store(datastore sysrepoapi.Datastore) *node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	root, ok := c.stores[datastore]
+	if !ok {
+		root = &node{}
+		c.stores[datastore] = root
+	}
+	return root
+}
+
+// subscribers returns the subscriptions to a datastore by descending priority.
+func (c *Connection) subscribers(datastore sysrepoapi.Datastore) []*Subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var result []*Subscription
+	for _, sub := range c.subscriptions {
+		if sub.ds == datastore {
+			result = append(result, sub)
+		}
+	}
+	slices.SortStableFunc(result, func(a, b *Subscription) int {
+		switch {
+		case a.priority > b.priority:
+			return -1
+		case a.priority < b.priority:
+			return 1
+		}
+		return 0
+	})
+	return result
+}
+
+type edit struct {
+	path        xpath.Path
+	delete      bool
+	move        bool
+	value       *string
+	position    sysrepoapi.MovePosition
+	keysOrValue *string
+	opts        sysrepoapi.EditOptions
+}
+
+func (e edit) apply(ed *editor) error {
+	switch {
+	case e.delete:
+		return ed.delete(e.path, e.opts)
+	case e.move:
+		return ed.move(e.path, e.position, e.keysOrValue, e.opts)
+	}
+	return ed.set(e.path, e.value, e.opts)
+}
+
+// Session prepares edits of a datastore until they are applied.
+type Session struct {
+	conn  *Connection
+	ds    sysrepoapi.Datastore
+	edits []edit
+	view  *node // Data seen by an event session
+}
+
+func (s *Session) ActiveDatastore() sysrepoapi.Datastore {
+	return s.ds
+}
+
+func (s *Session) SwitchDatastore(datastore sysrepoapi.Datastore) error {
+	err := checkDatastore(datastore)
+	if err != nil {
+		return err
+	}
+	s.ds = datastore
+	return nil
+}
+
+// GetItem returns the value of a leaf or leaf-list entry. Prepared edits are
+// not visible until applied.
+func (s *Session) GetItem(path string) (string, error) {
+	parsed, err := xpath.Parse(path)
+	if err != nil {
+		return "", invalidPath(path, err.Error())
+	}
+
+	root := s.view
+	if root == nil {
+		root = s.conn.store(s.ds)
+	}
+
+	chain, err := (&editor{root: root}).resolve(parsed)
+	if err != nil {
+		return "", err
+	}
+	if len(chain) < len(parsed) {
+		return "", sysrepoapi.Error{
+			Message: "Couldn't get '" + path + "'",
+			Code:    sysrepoapi.ErrNotFound,
+		}
+	}
+
+	value := chain[len(chain)-1].value
+	if value == nil {
+		return "", sysrepoapi.Error{
+			Message: "Node '" + path + "' has no value",
+			Code:    sysrepoapi.ErrInvalArg,
+		}
+	}
+	return *value, nil
+}
+
+func (s *Session) SetItem(path string, value *string, opts sysrepoapi.EditOptions) error {
+	return s.prepare(path, edit{value: value, opts: opts})
+}
+
+func (s *Session) DeleteItem(path string, opts sysrepoapi.EditOptions) error {
+	return s.prepare(path, edit{delete: true, opts: opts})
+}
+
+// MoveItem moves a list or leaf-list entry, creating it if needed. The origin
+// is ignored.
+func (s *Session) MoveItem(path string, position sysrepoapi.MovePosition, keysOrValue *string, origin *string, opts sysrepoapi.EditOptions) error {
+	return s.prepare(path, edit{move: true, position: position, keysOrValue: keysOrValue, opts: opts})
+}
+
+func (s *Session) prepare(path string, e edit) error {
+	parsed, err := xpath.Parse(path)
+	if err != nil {
+		return invalidPath(path, err.Error())
+	}
+	// Like libyang, accept only literal key values in the paths of edits.
+	_, err = parsed.DataPath()
+	if err != nil {
+		return invalidPath(path, err.Error())
+	}
+	e.path = parsed
+	s.edits = append(s.edits, e)
+	return nil
+}
+
+// ApplyChanges applies the prepared edits and notifies the subscribers. The
+// timeout is ignored. On failure the edits are kept, as with sysrepo.
+func (s *Session) ApplyChanges(timeout time.Duration) error {
+	if s.view != nil {
+		return sysrepoapi.Error{
+			Message: "Couldn't apply changes in an event session",
+			Code:    sysrepoapi.ErrUnsupported,
+		}
+	}
+	if len(s.edits) == 0 {
+		return nil
+	}
+
+	s.conn.applyMu.Lock()
+	defer s.conn.applyMu.Unlock()
+
+	ed := &editor{root: s.conn.store(s.ds).clone()}
+	for _, e := range s.edits {
+		err := e.apply(ed)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ed.changes) == 0 {
+		s.edits = nil
+		return nil
+	}
+
+	subscribers := s.conn.subscribers(s.ds)
+	for _, sub := range subscribers {
+		if sub.opts&sysrepoapi.SubsUpdate == 0 {
+			continue
+		}
+		changes := sub.filter(ed.changes)
+		if len(changes) == 0 {
+			continue
+		}
+
+		eventSess := &Session{conn: s.conn, ds: s.ds, view: ed.root}
+		err := sub.callback(eventSess, sub.moduleName, sub.xpath, sysrepoapi.EvUpdate, changes)
+		if err != nil {
+			return callbackError(err)
+		}
+		for _, e := range eventSess.edits {
+			err = e.apply(ed)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var notified []*Subscription
+	for _, sub := range subscribers {
+		if sub.opts&sysrepoapi.SubsDoneOnly != 0 {
+			continue
+		}
+		changes := sub.filter(ed.changes)
+		if len(changes) == 0 {
+			continue
+		}
+
+		err := sub.callback(&Session{conn: s.conn, ds: s.ds, view: ed.root}, sub.moduleName, sub.xpath, sysrepoapi.EvChange, changes)
+		if err != nil {
+			// Like sysrepo, abort in the reverse order of the notifications.
+			for i := len(notified) - 1; i >= 0; i-- {
+				done := notified[i]
+				done.callback(&Session{conn: s.conn, ds: s.ds, view: ed.root}, done.moduleName, done.xpath, sysrepoapi.EvAbort, done.filter(ed.changes))
+			}
+			return callbackError(err)
+		}
+		notified = append(notified, sub)
+	}
+
+	s.conn.mu.Lock()
+	s.conn.stores[s.ds] = ed.root
+	s.conn.mu.Unlock()
+	s.edits = nil
+
+	for _, sub := range subscribers {
+		changes := sub.filter(ed.changes)
+		if len(changes) > 0 {
+			sub.callback(&Session{conn: s.conn, ds: s.ds, view: ed.root}, sub.moduleName, sub.xpath, sysrepoapi.EvDone, changes)
+		}
+	}
+	return nil
+}
+
+// DiscardChanges drops the prepared edits of nodes under xpath, or all of
+// them.
+func (s *Session) DiscardChanges(xpath *string) error {
+	if xpath == nil {
+		s.edits = nil
+		return nil
+	}
+
+	s.edits = slices.DeleteFunc(s.edits, func(e edit) bool {
+		return under(e.path.String(), *xpath)
+	})
+	return nil
+}
+
+// ModuleChangeSubscribe subscribes to the changes of moduleName in the active
+// datastore. The xpath is matched as a prefix of the changed paths.
+func (s *Session) ModuleChangeSubscribe(moduleName string, xpath *string, callback sysrepoapi.ModuleChangeCallback, priority uint32, opts sysrepoapi.SubscribeOptions) (sysrepoapi.Subscription, error) {
+	sub := &Subscription{
+		conn:       s.conn,
+		ds:         s.ds,
+		moduleName: moduleName,
+		xpath:      xpath,
+		callback:   callback,
+		priority:   priority,
+		opts:       opts,
+	}
+
+	s.conn.applyMu.Lock()
+	defer s.conn.applyMu.Unlock()
+
+	if opts&sysrepoapi.SubsEnabled != 0 {
+		root := s.conn.store(s.ds)
+		changes := sub.filter(snapshot(root))
+
+		err := callback(&Session{conn: s.conn, ds: s.ds, view: root}, moduleName, xpath, sysrepoapi.EvEnabled, changes)
+		if err != nil {
+			return nil, callbackError(err)
+		}
+		callback(&Session{conn: s.conn, ds: s.ds, view: root}, moduleName, xpath, sysrepoapi.EvDone, changes)
+	}
+
+	s.conn.mu.Lock()
+	s.conn.subscriptions = append(s.conn.subscriptions, sub)
+	s.conn.mu.Unlock()
+	return sub, nil
+}
+
+// Close drops the prepared edits.
+func (s *Session) Close() {
+	s.edits = nil
+}
+
+// Subscription is a module change subscription.
+type Subscription struct {
+	conn       *Connection
+	ds         sysrepoapi.Datastore
+	moduleName string
+	xpath      *string
+	callback   sysrepoapi.ModuleChangeCallback
+	priority   uint32
+	opts       sysrepoapi.SubscribeOptions
+}
+
+func (s *Subscription) Close() {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+
+	s.conn.subscriptions = slices.DeleteFunc(s.conn.subscriptions, func(sub *Subscription) bool {
+		return sub == s
+	})
+}
+
+// filter returns the changes of the subscribed module under the xpath.
+func (s *Subscription) filter(changes []sysrepoapi.Change) []sysrepoapi.Change {
+	var result []sysrepoapi.Change
+	for _, change := range changes {
+		if !strings.HasPrefix(change.Path, "/"+s.moduleName+":") {
+			continue
+		}
+		if s.xpath != nil && !under(change.Path, *s.xpath) {
+			continue
+		}
+		result = append(result, change)
+	}
+	return result
+}
+
+// under reports whether path is prefix or a node below it.
+func under(path string, prefix string) bool {
+	rest, ok := strings.CutPrefix(path, prefix)
+	return ok && (rest == "" || rest[0] == '/' || rest[0] == '[')
+}
+
+func checkDatastore(datastore sysrepoapi.Datastore) error {
+	if datastore < sysrepoapi.DSRunning || datastore > sysrepoapi.DSFactoryDefault {
+		return sysrepoapi.Error{
+			Message: fmt.Sprintf("Unknown datastore %d", datastore),
+			Code:    sysrepoapi.ErrInvalArg,
+		}
+	}
+	return nil
+}
+
+// callbackError returns the error of a failed callback, keeping the code of a
+// sysrepoapi.Error.
+func callbackError(err error) error {
+	code := sysrepoapi.ErrCallbackFailed
+	var apiErr sysrepoapi.Error
+	if errors.As(err, &apiErr) {
+		if err == error(apiErr) {
+			return apiErr
+		}
+		code = apiErr.Code
+	}
+	return sysrepoapi.Error{
+		Message: err.Error(),
+		Code:    code,
+	}
+}
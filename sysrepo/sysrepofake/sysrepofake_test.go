@@ -0,0 +1,304 @@
+package sysrepofake_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepoapi"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepofake"
+)
+
+var eventNames = map[sysrepoapi.Event]string{
+	sysrepoapi.EvChange:  "change",
+	sysrepoapi.EvDone:    "done",
+	sysrepoapi.EvAbort:   "abort",
+	sysrepoapi.EvEnabled: "enabled",
+	sysrepoapi.EvUpdate:  "update",
+}
+
+var operationNames = map[sysrepoapi.ChangeOperation]string{
+	sysrepoapi.OpCreated:  "created",
+	sysrepoapi.OpModified: "modified",
+	sysrepoapi.OpDeleted:  "deleted",
+	sysrepoapi.OpMoved:    "moved",
+}
+
+func str(s string) *string {
+	return &s
+}
+
+// format describes a change as "operation path[=value][ after previous]".
+func format(change sysrepoapi.Change) string {
+	result := operationNames[change.Operation] + " " + change.Path
+	if change.Value != nil {
+		result += "=" + *change.Value
+	}
+	switch {
+	case change.PreviousValue != nil:
+		result += " after " + *change.PreviousValue
+	case change.PreviousList != nil:
+		result += " after " + *change.PreviousList
+	}
+	return result
+}
+
+func session(t *testing.T, conn *sysrepofake.Connection) sysrepoapi.Session {
+	t.Helper()
+
+	sess, err := conn.SessionStart(sysrepoapi.DSRunning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sess.Close)
+	return sess
+}
+
+// recorder subscribes to module m and records the changes of each event.
+type recorder struct {
+	changes [][]string
+}
+
+func (r *recorder) subscribe(t *testing.T, sess sysrepoapi.Session) {
+	t.Helper()
+
+	_, err := sess.ModuleChangeSubscribe("m", nil, func(_ sysrepoapi.Session, _ string, _ *string, event sysrepoapi.Event, changes []sysrepoapi.Change) error {
+		if event != sysrepoapi.EvChange {
+			return nil
+		}
+		var formatted []string
+		for _, change := range changes {
+			formatted = append(formatted, format(change))
+		}
+		r.changes = append(r.changes, formatted)
+		return nil
+	}, 0, sysrepoapi.SubsDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (r *recorder) apply(t *testing.T, sess sysrepoapi.Session, want ...string) {
+	t.Helper()
+
+	r.changes = nil
+	err := sess.ApplyChanges(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	if len(r.changes) == 1 {
+		got = r.changes[0]
+	} else if len(r.changes) > 1 {
+		t.Fatalf("changes reported %d times", len(r.changes))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSetChanges(t *testing.T) {
+	sess := session(t, sysrepofake.New())
+	r := &recorder{}
+	r.subscribe(t, sess)
+
+	sess.SetItem("/m:interfaces/interface[name='eth0']/mtu", str("1500"), sysrepoapi.EditDefault)
+	r.apply(t, sess,
+		"created /m:interfaces",
+		"created /m:interfaces/interface[name='eth0']",
+		"created /m:interfaces/interface[name='eth0']/name=eth0",
+		"created /m:interfaces/interface[name='eth0']/mtu=1500",
+	)
+
+	sess.SetItem("/m:interfaces/interface[name='eth0']/mtu", str("9000"), sysrepoapi.EditDefault)
+	sess.SetItem("/m:interfaces/interface[name='eth0']/n:enabled", str("true"), sysrepoapi.EditDefault)
+	r.apply(t, sess,
+		"modified /m:interfaces/interface[name='eth0']/mtu=9000 after 1500",
+		"created /m:interfaces/interface[name='eth0']/n:enabled=true",
+	)
+
+	value, err := sess.GetItem("/m:interfaces/interface[name='eth0']/mtu")
+	if err != nil || value != "9000" {
+		t.Errorf("GetItem() = %s, %v", value, err)
+	}
+
+	err = sess.SetItem("/m:interfaces/interface[name='eth0']/mtu", str("1500"), sysrepoapi.EditStrict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sess.ApplyChanges(0)
+	var apiErr sysrepoapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != sysrepoapi.ErrExists {
+		t.Errorf("strict SetItem of an existing node: %v", err)
+	}
+}
+
+func TestDeleteChanges(t *testing.T) {
+	sess := session(t, sysrepofake.New())
+	sess.SetItem("/m:interfaces/interface[name='eth0']/mtu", str("1500"), sysrepoapi.EditDefault)
+	sess.SetItem("/m:interfaces/interface[name='eth1']", nil, sysrepoapi.EditDefault)
+	if err := sess.ApplyChanges(0); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &recorder{}
+	r.subscribe(t, sess)
+
+	sess.DeleteItem("/m:interfaces/interface[name='eth0']", sysrepoapi.EditDefault)
+	sess.DeleteItem("/m:interfaces/interface[name='eth2']", sysrepoapi.EditDefault)
+	r.apply(t, sess,
+		"deleted /m:interfaces/interface[name='eth0']",
+		"deleted /m:interfaces/interface[name='eth0']/name=eth0",
+		"deleted /m:interfaces/interface[name='eth0']/mtu=1500",
+	)
+
+	_, err := sess.GetItem("/m:interfaces/interface[name='eth0']/mtu")
+	var apiErr sysrepoapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != sysrepoapi.ErrNotFound {
+		t.Errorf("GetItem() of a deleted node: %v", err)
+	}
+
+	sess.DeleteItem("/m:interfaces/interface[name='eth2']", sysrepoapi.EditStrict)
+	err = sess.ApplyChanges(0)
+	if !errors.As(err, &apiErr) || apiErr.Code != sysrepoapi.ErrNotFound {
+		t.Errorf("strict DeleteItem of a missing node: %v", err)
+	}
+}
+
+func TestMoveChanges(t *testing.T) {
+	sess := session(t, sysrepofake.New())
+	for _, server := range []string{"a", "b", "c"} {
+		sess.SetItem("/m:servers/server[.='"+server+"']", nil, sysrepoapi.EditDefault)
+		sess.SetItem("/m:users/user[name='"+server+"']", nil, sysrepoapi.EditDefault)
+	}
+	if err := sess.ApplyChanges(0); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &recorder{}
+	r.subscribe(t, sess)
+
+	sess.MoveItem("/m:servers/server[.='c']", sysrepoapi.MoveFirst, nil, nil, sysrepoapi.EditDefault)
+	r.apply(t, sess, "moved /m:servers/server[.='c']=c after ")
+
+	sess.MoveItem("/m:servers/server[.='c']", sysrepoapi.MoveAfter, str("a"), nil, sysrepoapi.EditDefault)
+	r.apply(t, sess, "moved /m:servers/server[.='c']=c after a")
+
+	sess.MoveItem("/m:users/user[name='a']", sysrepoapi.MoveLast, nil, nil, sysrepoapi.EditDefault)
+	r.apply(t, sess, "moved /m:users/user[name='a'] after [name='c']")
+
+	sess.MoveItem("/m:users/user[name='a']", sysrepoapi.MoveBefore, str("[name='b']"), nil, sysrepoapi.EditDefault)
+	r.apply(t, sess, "moved /m:users/user[name='a'] after ")
+
+	// Moving an entry to its current position changes nothing.
+	sess.MoveItem("/m:users/user[name='a']", sysrepoapi.MoveFirst, nil, nil, sysrepoapi.EditDefault)
+	r.apply(t, sess)
+}
+
+func TestEditPathsNeedLiterals(t *testing.T) {
+	sess := session(t, sysrepofake.New())
+
+	err := sess.SetItem(`/m:users/user[name=concat('it', "'", 's "x"')]`, nil, sysrepoapi.EditDefault)
+	var apiErr sysrepoapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != sysrepoapi.ErrInvalArg {
+		t.Errorf("SetItem() with concat(): %v", err)
+	}
+}
+
+// events subscribes callbacks named by their priority to module m which
+// record the events they receive, and fail in the event given by fail.
+func events(t *testing.T, sess sysrepoapi.Session, fail map[string]sysrepoapi.Event, opts map[string]sysrepoapi.SubscribeOptions, priorities ...uint32) *[]string {
+	t.Helper()
+
+	var result []string
+	for _, priority := range priorities {
+		name := fmt.Sprint(priority)
+		_, err := sess.ModuleChangeSubscribe("m", nil, func(_ sysrepoapi.Session, _ string, _ *string, event sysrepoapi.Event, _ []sysrepoapi.Change) error {
+			result = append(result, name+":"+eventNames[event])
+			if failing, ok := fail[name]; ok && failing == event {
+				return fmt.Errorf("rejected by %s: %w", name, sysrepoapi.Error{Message: "invalid", Code: sysrepoapi.ErrValidationFailed})
+			}
+			return nil
+		}, priority, opts[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &result
+}
+
+func TestEventOrder(t *testing.T) {
+	sess := session(t, sysrepofake.New())
+	got := events(t, sess, nil, map[string]sysrepoapi.SubscribeOptions{
+		"5": sysrepoapi.SubsUpdate,
+		"3": sysrepoapi.SubsDoneOnly,
+	}, 1, 5, 3, 10)
+
+	sess.SetItem("/m:a", str("1"), sysrepoapi.EditDefault)
+	if err := sess.ApplyChanges(0); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"5:update", "10:change", "5:change", "1:change", "10:done", "5:done", "3:done", "1:done"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("events %v, want %v", *got, want)
+	}
+}
+
+func TestAbortOrder(t *testing.T) {
+	sess := session(t, sysrepofake.New())
+	got := events(t, sess, map[string]sysrepoapi.Event{"2": sysrepoapi.EvChange}, nil, 1, 2, 3, 4)
+
+	sess.SetItem("/m:a", str("1"), sysrepoapi.EditDefault)
+	err := sess.ApplyChanges(0)
+
+	var apiErr sysrepoapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != sysrepoapi.ErrValidationFailed {
+		t.Errorf("ApplyChanges() = %v, want the code of the wrapped callback error", err)
+	}
+
+	want := []string{"4:change", "3:change", "2:change", "3:abort", "4:abort"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("events %v, want %v", *got, want)
+	}
+
+	_, err = sess.GetItem("/m:a")
+	if err == nil {
+		t.Error("rejected changes were stored")
+	}
+
+	// The edits are kept after a failure, as with sysrepo.
+	if err := sess.DiscardChanges(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.ApplyChanges(0); err != nil {
+		t.Errorf("ApplyChanges() after DiscardChanges() = %v", err)
+	}
+}
+
+func TestEnabledReplay(t *testing.T) {
+	sess := session(t, sysrepofake.New())
+	sess.SetItem("/m:a/b", str("1"), sysrepoapi.EditDefault)
+	if err := sess.ApplyChanges(0); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	_, err := sess.ModuleChangeSubscribe("m", nil, func(_ sysrepoapi.Session, _ string, _ *string, event sysrepoapi.Event, changes []sysrepoapi.Change) error {
+		for _, change := range changes {
+			got = append(got, eventNames[event]+" "+format(change))
+		}
+		return nil
+	}, 0, sysrepoapi.SubsEnabled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"enabled created /m:a", "enabled created /m:a/b=1", "done created /m:a", "done created /m:a/b=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,379 @@
+package sysrepofake
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepoapi"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/xpath"
+)
+
+// node is a data node of a datastore. Without a schema, list entries are told
+// apart only by the key predicates of the paths that created them, and
+// leaf-list entries by a [.='value'] predicate.
+type node struct {
+	module   string
+	name     string
+	keys     []xpath.Key
+	value    *string
+	children []*node
+}
+
+func (n *node) clone() *node {
+	result := *n
+	result.children = make([]*node, len(n.children))
+	for i, child := range n.children {
+		result.children[i] = child.clone()
+	}
+	return &result
+}
+
+func (n *node) matches(module string, segment xpath.Segment) bool {
+	if n.module != module || n.name != segment.Name || len(n.keys) != len(segment.Keys) {
+		return false
+	}
+	for _, key := range segment.Keys {
+		if !slices.Contains(n.keys, key) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *node) child(module string, segment xpath.Segment) int {
+	return slices.IndexFunc(n.children, func(child *node) bool {
+		return child.matches(module, segment)
+	})
+}
+
+// isLeafListEntry reports whether the node is selected by its own value.
+func (n *node) isLeafListEntry() bool {
+	return len(n.keys) == 1 && n.keys[0].Name == "."
+}
+
+func (n *node) segment(parentModule string) xpath.Segment {
+	segment := xpath.Segment{Name: n.name, Keys: n.keys}
+	if n.module != parentModule {
+		segment.Module = n.module
+	}
+	return segment
+}
+
+// pathOf returns the path of the last node of chain, which starts below the
+// root.
+func pathOf(chain []*node) string {
+	path := make(xpath.Path, len(chain))
+	module := ""
+	for i, n := range chain {
+		path[i] = n.segment(module)
+		module = n.module
+	}
+	return path.String()
+}
+
+// predicates returns the key predicates of a list entry, such as
+// [name='eth0'].
+func predicates(keys []xpath.Key) string {
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString("[" + key.Name + "=" + xpath.Quote(key.Value) + "]")
+	}
+	return b.String()
+}
+
+// editor applies edits to a tree and records the resulting changes.
+type editor struct {
+	root    *node
+	changes []sysrepoapi.Change
+}
+
+// resolve returns the chain of nodes from the top-level node to the node at
+// path, shorter than path if the node does not exist.
+func (e *editor) resolve(path xpath.Path) ([]*node, error) {
+	var chain []*node
+	parent := e.root
+	module := ""
+	for _, segment := range path {
+		if segment.Module != "" {
+			module = segment.Module
+		}
+		if module == "" {
+			return nil, invalidPath(path.String(), "the top-level node has no module")
+		}
+
+		i := parent.child(module, segment)
+		if i < 0 {
+			break
+		}
+		parent = parent.children[i]
+		chain = append(chain, parent)
+	}
+	return chain, nil
+}
+
+func (e *editor) set(path xpath.Path, value *string, opts sysrepoapi.EditOptions) error {
+	chain, err := e.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	if len(chain) == len(path) {
+		if opts&sysrepoapi.EditStrict != 0 {
+			return sysrepoapi.Error{
+				Message: "Node '" + path.String() + "' already exists",
+				Code:    sysrepoapi.ErrExists,
+			}
+		}
+
+		last := chain[len(chain)-1]
+		if value == nil || last.isLeafListEntry() || (last.value != nil && *last.value == *value) {
+			return nil
+		}
+
+		e.changes = append(e.changes, sysrepoapi.Change{
+			Operation:     sysrepoapi.OpModified,
+			Path:          pathOf(chain),
+			Value:         value,
+			PreviousValue: last.value,
+		})
+		last.value = value
+		return nil
+	}
+
+	if opts&sysrepoapi.EditNonRecursive != 0 && len(chain) < len(path)-1 {
+		return sysrepoapi.Error{
+			Message: "Parent of '" + path.String() + "' does not exist",
+			Code:    sysrepoapi.ErrNotFound,
+		}
+	}
+
+	parent := e.root
+	if len(chain) > 0 {
+		parent = chain[len(chain)-1]
+	}
+
+	for i := len(chain); i < len(path); i++ {
+		segment := path[i]
+		created := &node{module: parent.module, name: segment.Name, keys: segment.Keys}
+		if segment.Module != "" {
+			created.module = segment.Module
+		}
+
+		switch {
+		case created.isLeafListEntry():
+			created.value = &segment.Keys[0].Value
+		case i == len(path)-1:
+			created.value = value
+		}
+
+		parent.children = append(parent.children, created)
+		chain = append(chain, created)
+		e.created(chain)
+
+		if !created.isLeafListEntry() {
+			for _, key := range segment.Keys {
+				keyValue := key.Value
+				leaf := &node{module: created.module, name: key.Name, value: &keyValue}
+				created.children = append(created.children, leaf)
+				e.created(append(chain, leaf))
+			}
+		}
+		parent = created
+	}
+	return nil
+}
+
+func (e *editor) created(chain []*node) {
+	e.changes = append(e.changes, sysrepoapi.Change{
+		Operation: sysrepoapi.OpCreated,
+		Path:      pathOf(chain),
+		Value:     chain[len(chain)-1].value,
+	})
+}
+
+func (e *editor) delete(path xpath.Path, opts sysrepoapi.EditOptions) error {
+	chain, err := e.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	if len(chain) < len(path) {
+		if opts&sysrepoapi.EditStrict != 0 {
+			return sysrepoapi.Error{
+				Message: "Node '" + path.String() + "' does not exist",
+				Code:    sysrepoapi.ErrNotFound,
+			}
+		}
+		return nil
+	}
+
+	parent := e.root
+	if len(chain) > 1 {
+		parent = chain[len(chain)-2]
+	}
+
+	e.deleted(chain)
+	parent.children = slices.DeleteFunc(parent.children, func(n *node) bool {
+		return n == chain[len(chain)-1]
+	})
+	return nil
+}
+
+// deleted records the deletion of the last node of chain and its descendants.
+func (e *editor) deleted(chain []*node) {
+	last := chain[len(chain)-1]
+	e.changes = append(e.changes, sysrepoapi.Change{
+		Operation: sysrepoapi.OpDeleted,
+		Path:      pathOf(chain),
+		Value:     last.value,
+	})
+
+	for _, child := range last.children {
+		e.deleted(append(slices.Clip(chain), child))
+	}
+}
+
+func (e *editor) move(path xpath.Path, position sysrepoapi.MovePosition, keysOrValue *string, opts sysrepoapi.EditOptions) error {
+	if len(path) == 0 || len(path[len(path)-1].Keys) == 0 {
+		return invalidPath(path.String(), "only list and leaf-list entries can be moved")
+	}
+
+	chain, err := e.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	existed := len(chain) == len(path)
+	if !existed {
+		err = e.set(path, nil, opts)
+		if err != nil {
+			return err
+		}
+		chain, _ = e.resolve(path)
+	}
+
+	moved := chain[len(chain)-1]
+	parent := e.root
+	if len(chain) > 1 {
+		parent = chain[len(chain)-2]
+	}
+
+	sibling := func(n *node) bool {
+		return n.module == moved.module && n.name == moved.name
+	}
+	before := e.preceding(parent, moved, sibling)
+
+	var anchor []xpath.Key
+	if position == sysrepoapi.MoveBefore || position == sysrepoapi.MoveAfter {
+		if keysOrValue == nil {
+			return invalidPath(path.String(), "the relative move has no anchor")
+		}
+
+		if moved.isLeafListEntry() {
+			anchor = []xpath.Key{{Name: ".", Value: *keysOrValue}}
+		} else {
+			anchorPath, err := xpath.Parse("/anchor" + *keysOrValue)
+			if err != nil {
+				return invalidPath(path.String(), err.Error())
+			}
+			anchor = anchorPath[0].Keys
+		}
+	}
+
+	parent.children = slices.DeleteFunc(parent.children, func(n *node) bool {
+		return n == moved
+	})
+
+	first := slices.IndexFunc(parent.children, sibling)
+	var index int
+	switch position {
+	case sysrepoapi.MoveFirst:
+		index = first
+	case sysrepoapi.MoveLast:
+		index = -1
+		for i, n := range parent.children {
+			if sibling(n) {
+				index = i + 1
+			}
+		}
+	default:
+		index = slices.IndexFunc(parent.children, func(n *node) bool {
+			return sibling(n) && n.matches(moved.module, xpath.Segment{Name: moved.name, Keys: anchor})
+		})
+		if index < 0 {
+			return sysrepoapi.Error{
+				Message: "Anchor of '" + path.String() + "' does not exist",
+				Code:    sysrepoapi.ErrNotFound,
+			}
+		}
+		if position == sysrepoapi.MoveAfter {
+			index++
+		}
+	}
+	if index < 0 {
+		index = len(parent.children)
+	}
+	parent.children = slices.Insert(parent.children, index, moved)
+
+	after := e.preceding(parent, moved, sibling)
+	if !existed || after == before {
+		return nil
+	}
+
+	change := sysrepoapi.Change{
+		Operation: sysrepoapi.OpMoved,
+		Path:      pathOf(chain),
+		Value:     moved.value,
+	}
+	previous := ""
+	if after != nil && moved.isLeafListEntry() {
+		previous = *after.value
+	} else if after != nil {
+		previous = predicates(after.keys)
+	}
+	if moved.isLeafListEntry() {
+		change.PreviousValue = &previous
+	} else {
+		change.PreviousList = &previous
+	}
+	e.changes = append(e.changes, change)
+	return nil
+}
+
+// preceding returns the sibling entry before n, nil if n is the first one.
+func (e *editor) preceding(parent *node, n *node, sibling func(*node) bool) *node {
+	var result *node
+	for _, child := range parent.children {
+		if child == n {
+			return result
+		}
+		if sibling(child) {
+			result = child
+		}
+	}
+	return nil
+}
+
+// snapshot returns the creation of all nodes below the root as changes, the
+// way SubsEnabled subscribers see the existing data.
+func snapshot(root *node) []sysrepoapi.Change {
+	e := &editor{}
+	var walk func(chain []*node)
+	walk = func(chain []*node) {
+		e.created(chain)
+		for _, child := range chain[len(chain)-1].children {
+			walk(append(slices.Clip(chain), child))
+		}
+	}
+	for _, top := range root.children {
+		walk([]*node{top})
+	}
+	return e.changes
+}
+
+func invalidPath(path string, reason string) error {
+	return sysrepoapi.Error{
+		Message: "Invalid path '" + path + "': " + reason,
+		Code:    sysrepoapi.ErrInvalArg,
+	}
+}
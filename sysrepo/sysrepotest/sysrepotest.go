@@ -0,0 +1,95 @@
+// Package sysrepotest runs integration tests against a throwaway sysrepo
+// repository, so tests neither see nor modify the host's repository.
+//
+// The repository is selected through the SYSREPO_REPOSITORY_PATH and
+// SYSREPO_SHM_PREFIX environment variables of the whole process, so tests
+// using this package cannot run in parallel.
+package sysrepotest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+)
+
+// shmDir is where sysrepo creates its shared memory files.
+const shmDir = "/dev/shm"
+
+// New creates an empty repository in a temporary directory, installs the YANG
+// modules at schemaPaths and returns a connection to it. Imports are searched
+// for in the directories of the schemas. The connection is closed and the
+// repository with its shared memory removed when the test finishes.
+func New(t testing.TB, schemaPaths ...string) *sysrepo.Connection {
+	t.Helper()
+
+	repo := t.TempDir()
+	prefix := shmPrefix(t)
+	t.Setenv("SYSREPO_REPOSITORY_PATH", repo)
+	t.Setenv("SYSREPO_SHM_PREFIX", prefix)
+	t.Cleanup(func() { removeShm(t, prefix) })
+
+	conn, err := sysrepo.Connect(sysrepo.ConnDefault)
+	if err != nil {
+		t.Fatalf("connecting to the test repository: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	if len(schemaPaths) > 0 {
+		var searchDirs string
+		for _, path := range schemaPaths {
+			if searchDirs != "" {
+				searchDirs += ":"
+			}
+			searchDirs += filepath.Dir(path)
+		}
+
+		err = conn.InstallModules(schemaPaths, &searchDirs)
+		if err != nil {
+			t.Fatalf("installing %v: %v", schemaPaths, err)
+		}
+	}
+
+	return conn
+}
+
+// Session starts a session on datastore that is stopped when the test
+// finishes, before its connection is closed.
+func Session(t testing.TB, conn *sysrepo.Connection, datastore sysrepo.Datastore) *sysrepo.Session {
+	t.Helper()
+
+	session, err := conn.SessionStart(datastore)
+	if err != nil {
+		t.Fatalf("starting a session: %v", err)
+	}
+	t.Cleanup(session.Close)
+	return session
+}
+
+// shmPrefix returns a prefix for the shared memory files unique to the test.
+func shmPrefix(t testing.TB) string {
+	var id [8]byte
+	_, err := rand.Read(id[:])
+	if err != nil {
+		t.Fatalf("generating a shared memory prefix: %v", err)
+	}
+	return "srtest" + hex.EncodeToString(id[:])
+}
+
+func removeShm(t testing.TB, prefix string) {
+	files, err := filepath.Glob(filepath.Join(shmDir, prefix+"*"))
+	if err != nil {
+		t.Errorf("listing shared memory of %s: %v", prefix, err)
+		return
+	}
+
+	for _, file := range files {
+		err = os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			t.Errorf("removing shared memory: %v", err)
+		}
+	}
+}
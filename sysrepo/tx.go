@@ -0,0 +1,141 @@
+package sysrepo
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var errTxDone = errors.New("transaction already committed or rolled back")
+
+// Tx is a set of edits of a session which is either applied as a whole or
+// discarded. Only one transaction of a session runs at a time.
+type Tx struct {
+	session *Session
+	mu      sync.Mutex // Guards done
+	done    bool
+}
+
+// Begin starts a transaction, waiting until ctx is done for a running
+// transaction of the session to finish. The transaction must end with Commit
+// or Rollback; one which is dropped is rolled back when garbage collected. It
+// fails if the session has edits prepared outside of a transaction.
+//
+// Edits made on the session outside of its transaction, e.g. with SetItem or
+// ApplyChanges from another goroutine, while a transaction is open are not
+// supported and would become part of it.
+func (s *Session) Begin(ctx context.Context) (*Tx, error) {
+	select {
+	case s.txSlot() <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if s.HasChanges() {
+		<-s.txSlot()
+		return nil, Error{
+			Message: "The session has edits prepared outside of a transaction",
+			Code:    ErrInvalArg,
+		}
+	}
+
+	tx := &Tx{session: s}
+	runtime.SetFinalizer(tx, (*Tx).Rollback)
+	return tx, nil
+}
+
+// txSlot returns the channel holding a value while a transaction runs.
+func (s *Session) txSlot() chan struct{} {
+	s.txOnce.Do(func() {
+		s.txRunning = make(chan struct{}, 1)
+	})
+	return s.txRunning
+}
+
+func (t *Tx) Set(path string, value *string, opts EditOptions) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return errTxDone
+	}
+	return t.session.SetItem(path, value, opts)
+}
+
+func (t *Tx) Delete(path string, opts EditOptions) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return errTxDone
+	}
+	return t.session.DeleteItem(path, opts)
+}
+
+func (t *Tx) Move(path string, position MovePosition, keysOrValue *string, origin *string, opts EditOptions) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return errTxDone
+	}
+	return t.session.MoveItem(path, position, keysOrValue, origin, opts)
+}
+
+// Commit applies the edits, using the deadline of ctx as the timeout. The
+// edits are discarded if they cannot be applied.
+func (t *Tx) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return errTxDone
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return errors.Join(err, t.discard())
+	}
+
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if timeout < time.Millisecond {
+			return errors.Join(context.DeadlineExceeded, t.discard())
+		}
+	}
+
+	err = t.session.ApplyChanges(timeout)
+	if err != nil {
+		return errors.Join(err, t.discard())
+	}
+
+	t.finish()
+	return nil
+}
+
+// Rollback discards the edits.
+func (t *Tx) Rollback() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return errTxDone
+	}
+	return t.discard()
+}
+
+// discard drops the edits and ends the transaction.
+func (t *Tx) discard() error {
+	err := t.session.DiscardChanges(nil)
+	t.finish()
+	return err
+}
+
+func (t *Tx) finish() {
+	t.done = true
+	runtime.SetFinalizer(t, nil)
+	<-t.session.txSlot()
+}
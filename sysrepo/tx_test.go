@@ -0,0 +1,38 @@
+package sysrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattiaswal/go-sysrepo/sysrepo"
+	"github.com/mattiaswal/go-sysrepo/sysrepo/sysrepotest"
+)
+
+func TestBeginWaitsForRunningTransaction(t *testing.T) {
+	conn := sysrepotest.New(t, "testdata/sysrepo-test.yang")
+	session := sysrepotest.Session(t, conn, sysrepo.DSRunning)
+
+	tx, err := session.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = session.Begin(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Begin() during a transaction = %v, want context.DeadlineExceeded", err)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err = session.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() after Rollback() = %v", err)
+	}
+	tx.Rollback()
+}
@@ -0,0 +1,354 @@
+// Package xpath builds and parses the data paths used by the sysrepo session
+// API, such as /ietf-interfaces:interfaces/interface[name='eth0']/mtu, taking
+// care of module prefixes and of quoting list key values.
+package xpath
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Key is a predicate selecting a list entry by a key, or a leaf-list entry by
+// its value when Name is ".".
+type Key struct {
+	Name  string
+	Value string
+}
+
+// Segment is a single node step of a path. Module is empty if the node is in
+// the same module as its parent.
+type Segment struct {
+	Module string
+	Name   string
+	Keys   []Key
+}
+
+// Path is a data path. Its methods never modify the receiver, so a path can be
+// used as the base of several others.
+type Path []Segment
+
+// New returns the path of a top-level node of module.
+func New(module string, name string) Path {
+	return Path{{Module: module, Name: name}}
+}
+
+// Child returns the path of the child called name, which may be prefixed with
+// a module name, e.g. "ietf-ip:ipv4".
+func (p Path) Child(name string) Path {
+	segment := Segment{Name: name}
+	if module, local, ok := strings.Cut(name, ":"); ok {
+		segment = Segment{Module: module, Name: local}
+	}
+
+	result := make(Path, len(p), len(p)+1)
+	copy(result, p)
+	return append(result, segment)
+}
+
+// Key returns the path with a key predicate added to its last node.
+func (p Path) Key(name string, value string) Path {
+	if len(p) == 0 {
+		return p
+	}
+
+	result := make(Path, len(p))
+	copy(result, p)
+
+	last := &result[len(result)-1]
+	keys := make([]Key, len(last.Keys), len(last.Keys)+1)
+	copy(keys, last.Keys)
+	last.Keys = append(keys, Key{Name: name, Value: value})
+	return result
+}
+
+// Value returns the path with a predicate selecting the leaf-list entry
+// value added to its last node.
+func (p Path) Value(value string) Path {
+	return p.Key(".", value)
+}
+
+// Parent returns the path of the parent node, or nil for a top-level node.
+func (p Path) Parent() Path {
+	if len(p) <= 1 {
+		return nil
+	}
+	return p[: len(p)-1 : len(p)-1]
+}
+
+// Module returns the module of the last node.
+func (p Path) Module() string {
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].Module != "" {
+			return p[i].Module
+		}
+	}
+	return ""
+}
+
+// ErrBothQuotes is returned by QuoteLiteral and DataPath for values
+// containing both single and double quotes.
+var ErrBothQuotes = errors.New("the value contains both single and double quotes")
+
+// String returns the path with module prefixes on the first node and on nodes
+// whose module differs from their parent's. Key values are quoted with Quote,
+// so the result may only be valid for reading; see DataPath.
+func (p Path) String() string {
+	result, _ := p.format(func(value string) (string, error) {
+		return Quote(value), nil
+	})
+	return result
+}
+
+// DataPath returns the path like String, for the data paths of edits, which
+// require every key value to be a single literal. It fails with ErrBothQuotes
+// if a key value can't be written so.
+func (p Path) DataPath() (string, error) {
+	return p.format(QuoteLiteral)
+}
+
+func (p Path) format(quote func(string) (string, error)) (string, error) {
+	var b strings.Builder
+	current := ""
+	for _, segment := range p {
+		b.WriteByte('/')
+		if segment.Module != "" && segment.Module != current {
+			b.WriteString(segment.Module)
+			b.WriteByte(':')
+			current = segment.Module
+		}
+		b.WriteString(segment.Name)
+
+		for _, key := range segment.Keys {
+			value, err := quote(key.Value)
+			if err != nil {
+				return "", fmt.Errorf("key %s: %w", key.Name, err)
+			}
+			b.WriteByte('[')
+			b.WriteString(key.Name)
+			b.WriteByte('=')
+			b.WriteString(value)
+			b.WriteByte(']')
+		}
+	}
+	return b.String(), nil
+}
+
+// Quote quotes a predicate value. Single quotes are used unless the value
+// contains them; a value containing both kinds of quotes is written as an
+// XPath concat() expression. libyang only accepts concat() in XPath evaluated
+// for reading, such as in GetItems or GetData, and not in the data paths of
+// edits, which need QuoteLiteral.
+func Quote(value string) string {
+	literal, err := QuoteLiteral(value)
+	if err == nil {
+		return literal
+	}
+
+	parts := strings.Split(value, "'")
+	quoted := make([]string, 0, 2*len(parts)-1)
+	for i, part := range parts {
+		if i > 0 {
+			quoted = append(quoted, "\"'\"")
+		}
+		if part != "" {
+			quoted = append(quoted, "'"+part+"'")
+		}
+	}
+	return "concat(" + strings.Join(quoted, ", ") + ")"
+}
+
+// QuoteLiteral quotes a predicate value as a single literal, in single quotes
+// unless the value contains them. It fails with ErrBothQuotes for values
+// containing both kinds of quotes, which no literal can express.
+func QuoteLiteral(value string) (string, error) {
+	switch {
+	case !strings.Contains(value, "'"):
+		return "'" + value + "'", nil
+	case !strings.Contains(value, "\""):
+		return "\"" + value + "\"", nil
+	}
+	return "", ErrBothQuotes
+}
+
+// Parse parses an absolute data path with key and leaf-list value predicates,
+// whose values are quoted literals or concat() expressions as written by
+// Quote.
+func Parse(s string) (Path, error) {
+	parser := &parser{input: s}
+
+	var result Path
+	for !parser.done() {
+		segment, err := parser.segment()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, segment)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("empty path")
+	}
+	return result, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid path %q at offset %d: %s", p.input, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for !p.done() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) expect(c byte) error {
+	p.skipSpace()
+	if p.done() || p.input[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) segment() (Segment, error) {
+	err := p.expect('/')
+	if err != nil {
+		return Segment{}, err
+	}
+
+	name, err := p.identifier()
+	if err != nil {
+		return Segment{}, err
+	}
+
+	segment := Segment{Name: name}
+	if !p.done() && p.input[p.pos] == ':' {
+		p.pos++
+		segment.Module = name
+		segment.Name, err = p.identifier()
+		if err != nil {
+			return Segment{}, err
+		}
+	}
+
+	for !p.done() && p.input[p.pos] == '[' {
+		p.pos++
+		key, err := p.predicate()
+		if err != nil {
+			return Segment{}, err
+		}
+		segment.Keys = append(segment.Keys, key)
+	}
+
+	return segment, nil
+}
+
+func (p *parser) identifier() (string, error) {
+	start := p.pos
+	for !p.done() {
+		c := p.input[p.pos]
+		if c == '/' || c == ':' || c == '[' || c == ']' || c == '=' || c == ' ' {
+			break
+		}
+		p.pos++
+	}
+
+	if p.pos == start {
+		return "", p.errorf("expected a name")
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *parser) predicate() (Key, error) {
+	p.skipSpace()
+
+	var key Key
+	if !p.done() && p.input[p.pos] == '.' {
+		p.pos++
+		key.Name = "."
+	} else {
+		name, err := p.identifier()
+		if err != nil {
+			return Key{}, err
+		}
+		if !p.done() && p.input[p.pos] == ':' {
+			p.pos++
+			local, err := p.identifier()
+			if err != nil {
+				return Key{}, err
+			}
+			name += ":" + local
+		}
+		key.Name = name
+	}
+
+	err := p.expect('=')
+	if err != nil {
+		return Key{}, err
+	}
+
+	p.skipSpace()
+	key.Value, err = p.value()
+	if err != nil {
+		return Key{}, err
+	}
+
+	return key, p.expect(']')
+}
+
+// value parses a quoted literal or a concat() of quoted literals.
+func (p *parser) value() (string, error) {
+	if !strings.HasPrefix(p.input[p.pos:], "concat(") {
+		return p.literal()
+	}
+	p.pos += len("concat(")
+
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		part, err := p.literal()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(part)
+
+		p.skipSpace()
+		if p.done() {
+			return "", p.errorf("unterminated concat()")
+		}
+		if p.input[p.pos] == ')' {
+			p.pos++
+			return b.String(), nil
+		}
+		err = p.expect(',')
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *parser) literal() (string, error) {
+	if p.done() || (p.input[p.pos] != '\'' && p.input[p.pos] != '"') {
+		return "", p.errorf("expected a quoted value")
+	}
+
+	quote := p.input[p.pos]
+	end := strings.IndexByte(p.input[p.pos+1:], quote)
+	if end < 0 {
+		return "", p.errorf("unterminated quoted value")
+	}
+
+	value := p.input[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return value, nil
+}
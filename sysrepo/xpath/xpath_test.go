@@ -0,0 +1,152 @@
+package xpath
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		value   string
+		quoted  string
+		literal bool
+	}{
+		{"eth0", "'eth0'", true},
+		{"", "''", true},
+		{"it's", `"it's"`, true},
+		{`say "hi"`, `'say "hi"'`, true},
+		{`it's "x"`, `concat('it', "'", 's "x"')`, false},
+		{`'"`, `concat("'", '"')`, false},
+		{`a'b'"`, `concat('a', "'", 'b', "'", '"')`, false},
+	}
+
+	for _, test := range tests {
+		quoted := Quote(test.value)
+		if quoted != test.quoted {
+			t.Errorf("Quote(%q) = %s, want %s", test.value, quoted, test.quoted)
+		}
+
+		literal, err := QuoteLiteral(test.value)
+		switch {
+		case test.literal && (err != nil || literal != test.quoted):
+			t.Errorf("QuoteLiteral(%q) = %s, %v, want %s", test.value, literal, err, test.quoted)
+		case !test.literal && !errors.Is(err, ErrBothQuotes):
+			t.Errorf("QuoteLiteral(%q) = %s, %v, want ErrBothQuotes", test.value, literal, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		path  Path
+		// output is the formatted path, the input if empty.
+		output string
+	}{
+		{
+			input: "/ietf-interfaces:interfaces",
+			path:  Path{{Module: "ietf-interfaces", Name: "interfaces"}},
+		},
+		{
+			input: "/ietf-interfaces:interfaces/interface[name='eth0']/ietf-ip:ipv4/mtu",
+			path: Path{
+				{Module: "ietf-interfaces", Name: "interfaces"},
+				{Name: "interface", Keys: []Key{{Name: "name", Value: "eth0"}}},
+				{Module: "ietf-ip", Name: "ipv4"},
+				{Name: "mtu"},
+			},
+		},
+		{
+			input:  `/m:list[a="x'y"][ b = 'z' ]`,
+			path:   Path{{Module: "m", Name: "list", Keys: []Key{{Name: "a", Value: "x'y"}, {Name: "b", Value: "z"}}}},
+			output: `/m:list[a="x'y"][b='z']`,
+		},
+		{
+			input: "/m:leaf-list[.='1']",
+			path:  Path{{Module: "m", Name: "leaf-list", Keys: []Key{{Name: ".", Value: "1"}}}},
+		},
+		{
+			input: `/m:list[a=concat('it', "'", 's "x"')]`,
+			path:  Path{{Module: "m", Name: "list", Keys: []Key{{Name: "a", Value: `it's "x"`}}}},
+		},
+		{
+			input:  "/m:a/m:b/n:c",
+			path:   Path{{Module: "m", Name: "a"}, {Module: "m", Name: "b"}, {Module: "n", Name: "c"}},
+			output: "/m:a/b/n:c",
+		},
+	}
+
+	for _, test := range tests {
+		path, err := Parse(test.input)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(path, test.path) {
+			t.Errorf("Parse(%q) = %#v, want %#v", test.input, path, test.path)
+		}
+
+		output := test.output
+		if output == "" {
+			output = test.input
+		}
+		if path.String() != output {
+			t.Errorf("Parse(%q).String() = %s, want %s", test.input, path.String(), output)
+		}
+
+		reparsed, err := Parse(output)
+		if err != nil || reparsed.String() != output {
+			t.Errorf("Parse(%q) = %s, %v, want the same path", output, reparsed, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"m:a",
+		"/",
+		"/m:a[name]",
+		"/m:a[name='x'",
+		"/m:a[name='x]",
+		"/m:a[name=x]",
+		"/m:a[name=concat('x'",
+	} {
+		path, err := Parse(input)
+		if err == nil {
+			t.Errorf("Parse(%q) = %#v, want an error", input, path)
+		}
+	}
+}
+
+func TestDataPath(t *testing.T) {
+	path := New("m", "list").Key("name", "it's").Child("n:leaf")
+	dataPath, err := path.DataPath()
+	if err != nil || dataPath != `/m:list[name="it's"]/n:leaf` {
+		t.Errorf("DataPath() = %s, %v", dataPath, err)
+	}
+
+	path = New("m", "list").Key("name", `it's "x"`)
+	_, err = path.DataPath()
+	if !errors.Is(err, ErrBothQuotes) {
+		t.Errorf("DataPath() of %s = %v, want ErrBothQuotes", path, err)
+	}
+}
+
+func TestPathIsImmutable(t *testing.T) {
+	base := New("m", "a")
+	first := base.Child("b").Key("k", "1")
+	second := base.Child("c")
+	third := first.Key("l", "2")
+
+	if base.String() != "/m:a" || first.String() != "/m:a/b[k='1']" || second.String() != "/m:a/c" || third.String() != "/m:a/b[k='1'][l='2']" {
+		t.Errorf("paths changed: %s %s %s %s", base, first, second, third)
+	}
+	if first.Parent().String() != "/m:a" || base.Parent() != nil {
+		t.Errorf("Parent() = %s, %s", first.Parent(), base.Parent())
+	}
+	if third.Module() != "m" || New("m", "a").Child("n:b").Module() != "n" {
+		t.Errorf("Module() = %s", third.Module())
+	}
+}